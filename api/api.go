@@ -1,17 +1,24 @@
 package api
 
 import (
+	"strings"
+
 	"../db"
+	"./times"
 )
 
 type Config struct {
-	DbAPI db.DbAPI
+	DbAPI     db.DbAPI
+	Uri       string
+	Durations times.Formatter
 }
 
 type Api struct {
-	DbAPI db.DbAPI
+	DbAPI     db.DbAPI
+	Uri       string
+	Durations times.Formatter
 }
 
 func New(cfg Config) Api {
-	return Api{DbAPI: cfg.DbAPI}
+	return Api{DbAPI: cfg.DbAPI, Uri: strings.TrimRight(cfg.Uri, "/"), Durations: cfg.Durations}
 }
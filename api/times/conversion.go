@@ -1,22 +1,131 @@
 package times
 
 import (
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"../../apierror"
+)
+
+type Style int
+
+const (
+	Short Style = iota
+	Long
 )
 
-func HumanTimeConversion(seconds int64, styleType string, limitKey string, separator string) (humanTime string) {
-	timeMap := [][]interface{}{[]interface{}{"mo", "month", int64(18144000)}, []interface{}{"w", "week", int64(604800)}, []interface{}{"d", "day", int64(86400)}, []interface{}{"h", "hours", int64(3600)}, []interface{}{"m", "minutes", int64(60)}, []interface{}{"s", "second", int64(1)}}
-	unitMax := map[string]int{"month": 0, "week": 1, "day": 2, "hour": 3, "minute": 4, "second": 5}
-	unitKey := map[bool]int{true: 0, false: 1}[styleType == "short"]
-	var t int64
-	for n := unitMax[limitKey]; n < 6; n++ {
-		limit := timeMap[n][2].(int64)
-		if seconds >= limit {
-			t = seconds / limit
-			humanTime += separator + strconv.FormatInt(t, 10) + timeMap[n][unitKey].(string)
-			seconds -= t * limit
+// Formatter converts durations to and from human readable strings. Days and
+// weeks are working days and weeks, as in GitLab, and a month is four weeks.
+type Formatter struct {
+	HoursPerDay  int
+	DaysPerWeek  int
+	LimitToHours bool
+}
+
+var GitLab = Formatter{HoursPerDay: 8, DaysPerWeek: 5}
+
+var Calendar = Formatter{HoursPerDay: 24, DaysPerWeek: 7}
+
+type unit struct {
+	short    string
+	singular string
+	plural   string
+	seconds  int64
+}
+
+var humanTimePart = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(months?|weeks?|days?|hours?|minutes?|seconds?|mo|w|d|h|m|s)?`)
+
+func (f Formatter) units() []unit {
+	hoursPerDay := int64(f.HoursPerDay)
+	if hoursPerDay <= 0 {
+		hoursPerDay = int64(GitLab.HoursPerDay)
+	}
+	daysPerWeek := int64(f.DaysPerWeek)
+	if daysPerWeek <= 0 {
+		daysPerWeek = int64(GitLab.DaysPerWeek)
+	}
+	return []unit{
+		{"mo", "month", "months", 4 * daysPerWeek * hoursPerDay * 3600},
+		{"w", "week", "weeks", daysPerWeek * hoursPerDay * 3600},
+		{"d", "day", "days", hoursPerDay * 3600},
+		{"h", "hour", "hours", 3600},
+		{"m", "minute", "minutes", 60},
+		{"s", "second", "seconds", 1},
+	}
+}
+
+// Format renders seconds as "1w 2d 3h 30m" (Short) or
+// "1 week 2 days 3 hours 30 minutes" (Long), in the given locale.
+func (f Formatter) Format(seconds int64, style Style, locale string) string {
+	l, exists := locales[locale]
+	if !exists {
+		l = locales[DefaultLocale]
+	}
+	units := f.units()
+	if f.LimitToHours {
+		units = units[3:]
+	}
+	sign := ""
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	if seconds == 0 {
+		return l.label(units[len(units)-1].singular, 0, style)
+	}
+	parts := []string{}
+	for _, u := range units {
+		if seconds >= u.seconds {
+			parts = append(parts, l.label(u.singular, seconds/u.seconds, style))
+			seconds %= u.seconds
+		}
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// Parse reads durations such as "1w 2d 3h 30m", "1h30m", "1.5h", "2 days" or a
+// plain number of seconds, optionally prefixed with "-". A trailing number
+// without unit is read in the unit below the previous one ("3h30" is 3h 30m).
+func (f Formatter) Parse(humanTime string) (int64, error) {
+	humanTime = strings.TrimSpace(humanTime)
+	input := humanTime
+	sign := int64(1)
+	if strings.HasPrefix(humanTime, "-") {
+		sign = -1
+		humanTime = strings.TrimSpace(humanTime[1:])
+	}
+	if n, err := strconv.ParseInt(humanTime, 10, 64); err == nil {
+		return sign * n, nil
+	}
+	units := f.units()
+	unitIndex := map[string]int{}
+	for n, u := range units {
+		unitIndex[u.short] = n
+		unitIndex[u.singular] = n
+		unitIndex[u.plural] = n
+	}
+	parts := humanTimePart.FindAllStringSubmatch(humanTime, -1)
+	if len(parts) == 0 || strings.TrimSpace(humanTimePart.ReplaceAllString(humanTime, "")) != "" {
+		return 0, apierror.BadRequest("duration " + input + " is invalid")
+	}
+	var seconds float64
+	previous := -1
+	for n, part := range parts {
+		value, err := strconv.ParseFloat(part[1], 64)
+		if err != nil {
+			return 0, apierror.BadRequest("duration " + input + " is invalid")
+		}
+		index, exists := unitIndex[part[2]]
+		if !exists {
+			if n != len(parts)-1 || previous < 0 || previous+1 >= len(units) {
+				return 0, apierror.BadRequest("duration " + input + " is invalid")
+			}
+			index = previous + 1
 		}
+		seconds += value * float64(units[index].seconds)
+		previous = index
 	}
-	return strings.TrimLeft(humanTime, separator)
+	return sign * int64(math.Round(seconds)), nil
 }
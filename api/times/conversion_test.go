@@ -0,0 +1,63 @@
+package times
+
+import (
+	"testing"
+)
+
+func TestFormatterParse(t *testing.T) {
+	for _, test := range []struct {
+		input   string
+		seconds int64
+		invalid bool
+	}{
+		{input: "90", seconds: 90},
+		{input: "1h30m", seconds: 5400},
+		{input: "1d2h", seconds: 36000},
+		{input: "3h30", seconds: 12600},
+		{input: "1w 2d 3h 30m", seconds: 214200},
+		{input: "1.5h", seconds: 5400},
+		{input: "2 days", seconds: 57600},
+		{input: "1 hour 1 minute", seconds: 3660},
+		{input: "1mo", seconds: 576000},
+		{input: "-30m", seconds: -1800},
+		{input: "", invalid: true},
+		{input: "3x", invalid: true},
+		{input: "1h 30 2m", invalid: true},
+		{input: "10s5", invalid: true},
+	} {
+		seconds, err := GitLab.Parse(test.input)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("Parse(%q) = %d, want an error", test.input, seconds)
+			}
+			continue
+		}
+		if err != nil || seconds != test.seconds {
+			t.Errorf("Parse(%q) = %d, %v, want %d", test.input, seconds, err, test.seconds)
+		}
+	}
+}
+
+func TestFormatterFormat(t *testing.T) {
+	for _, test := range []struct {
+		formatter Formatter
+		seconds   int64
+		style     Style
+		locale    string
+		output    string
+	}{
+		{formatter: GitLab, seconds: 0, style: Short, locale: "en", output: "0s"},
+		{formatter: GitLab, seconds: 5400, style: Short, locale: "en", output: "1h 30m"},
+		{formatter: GitLab, seconds: 28800, style: Short, locale: "en", output: "1d"},
+		{formatter: GitLab, seconds: 214200, style: Short, locale: "en", output: "1w 2d 3h 30m"},
+		{formatter: GitLab, seconds: -5400, style: Short, locale: "en", output: "-1h 30m"},
+		{formatter: GitLab, seconds: 3660, style: Long, locale: "en", output: "1 hour 1 minute"},
+		{formatter: GitLab, seconds: 7320, style: Long, locale: "en", output: "2 hours 2 minutes"},
+		{formatter: Calendar, seconds: 86400, style: Short, locale: "en", output: "1d"},
+		{formatter: Formatter{LimitToHours: true}, seconds: 214200, style: Short, locale: "en", output: "59h 30m"},
+	} {
+		if output := test.formatter.Format(test.seconds, test.style, test.locale); output != test.output {
+			t.Errorf("Format(%d) = %q, want %q", test.seconds, output, test.output)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package times
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultLocale = "en"
+
+// Unit holds the labels of a time unit: Short is used by the Short style, One
+// and Other are the Long style singular and plural forms.
+type Unit struct {
+	Short string `yaml:"short"`
+	One   string `yaml:"one"`
+	Other string `yaml:"other"`
+}
+
+// Locale is a translation table. Units are keyed by month, week, day, hour,
+// minute and second; One lists the counts that take the singular form.
+type Locale struct {
+	Units map[string]Unit `yaml:"units"`
+	One   []int64         `yaml:"one"`
+}
+
+var locales = map[string]Locale{
+	"en": {
+		Units: map[string]Unit{
+			"month":  {"mo", "month", "months"},
+			"week":   {"w", "week", "weeks"},
+			"day":    {"d", "day", "days"},
+			"hour":   {"h", "hour", "hours"},
+			"minute": {"m", "minute", "minutes"},
+			"second": {"s", "second", "seconds"},
+		},
+		One: []int64{1},
+	},
+	"fr": {
+		Units: map[string]Unit{
+			"month":  {"mois", "mois", "mois"},
+			"week":   {"sem", "semaine", "semaines"},
+			"day":    {"j", "jour", "jours"},
+			"hour":   {"h", "heure", "heures"},
+			"minute": {"min", "minute", "minutes"},
+			"second": {"s", "seconde", "secondes"},
+		},
+		One: []int64{0, 1},
+	},
+	"de": {
+		Units: map[string]Unit{
+			"month":  {"Mon", "Monat", "Monate"},
+			"week":   {"W", "Woche", "Wochen"},
+			"day":    {"T", "Tag", "Tage"},
+			"hour":   {"Std", "Stunde", "Stunden"},
+			"minute": {"Min", "Minute", "Minuten"},
+			"second": {"s", "Sekunde", "Sekunden"},
+		},
+		One: []int64{1},
+	},
+	"es": {
+		Units: map[string]Unit{
+			"month":  {"mes", "mes", "meses"},
+			"week":   {"sem", "semana", "semanas"},
+			"day":    {"d", "día", "días"},
+			"hour":   {"h", "hora", "horas"},
+			"minute": {"min", "minuto", "minutos"},
+			"second": {"s", "segundo", "segundos"},
+		},
+		One: []int64{1},
+	},
+}
+
+// LoadLocales reads every <locale>.yml file of dir, adding new locales or
+// overriding the built-in ones. Missing units fall back to English.
+func LoadLocales(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var locale Locale
+		if err = yaml.UnmarshalStrict(content, &locale); err != nil {
+			return errors.New("invalid locale file " + path + ": " + err.Error())
+		}
+		for key := range locale.Units {
+			if _, exists := locales[DefaultLocale].Units[key]; !exists {
+				return errors.New("invalid locale file " + path + ": unknown unit " + key)
+			}
+		}
+		locales[strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".yml"))] = locale
+	}
+	return nil
+}
+
+// MatchLocale picks the best available locale for a locale parameter or an
+// Accept-Language header (e.g. "fr-CH, fr;q=0.9, en;q=0.8"), or DefaultLocale.
+func MatchLocale(acceptLanguage string) string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	tags := []tag{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		t := tag{name: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		for _, field := range fields[1:] {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "q=") {
+				if q, err := strconv.ParseFloat(field[2:], 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.name != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	for _, t := range tags {
+		name := strings.Replace(t.name, "_", "-", -1)
+		if _, exists := locales[name]; exists {
+			return name
+		}
+		if _, exists := locales[strings.Split(name, "-")[0]]; exists {
+			return strings.Split(name, "-")[0]
+		}
+	}
+	return DefaultLocale
+}
+
+func (l Locale) label(key string, n int64, style Style) string {
+	u, exists := l.Units[key]
+	if !exists {
+		u = locales[DefaultLocale].Units[key]
+	}
+	if style == Short {
+		return strconv.FormatInt(n, 10) + u.Short
+	}
+	ones := l.One
+	if len(ones) == 0 {
+		ones = locales[DefaultLocale].One
+	}
+	for _, one := range ones {
+		if n == one {
+			return strconv.FormatInt(n, 10) + " " + u.One
+		}
+	}
+	return strconv.FormatInt(n, 10) + " " + u.Other
+}
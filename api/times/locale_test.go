@@ -0,0 +1,51 @@
+package times
+
+import (
+	"testing"
+)
+
+func TestMatchLocale(t *testing.T) {
+	for _, test := range []struct {
+		acceptLanguage string
+		locale         string
+	}{
+		{acceptLanguage: "", locale: "en"},
+		{acceptLanguage: "fr", locale: "fr"},
+		{acceptLanguage: "FR", locale: "fr"},
+		{acceptLanguage: "fr-CH", locale: "fr"},
+		{acceptLanguage: "de_AT", locale: "de"},
+		{acceptLanguage: "fr-CH, fr;q=0.9, en;q=0.8", locale: "fr"},
+		{acceptLanguage: "en;q=0.5, es;q=0.8", locale: "es"},
+		{acceptLanguage: "ja, de;q=0.7", locale: "de"},
+		{acceptLanguage: "fr;q=0, de", locale: "de"},
+		{acceptLanguage: "*", locale: "en"},
+		{acceptLanguage: "ja, zh;q=0.9", locale: "en"},
+		{acceptLanguage: " , ;q=1", locale: "en"},
+	} {
+		if locale := MatchLocale(test.acceptLanguage); locale != test.locale {
+			t.Errorf("MatchLocale(%q) = %q, want %q", test.acceptLanguage, locale, test.locale)
+		}
+	}
+}
+
+func TestFormatLocale(t *testing.T) {
+	for _, test := range []struct {
+		seconds int64
+		style   Style
+		locale  string
+		output  string
+	}{
+		{seconds: 0, style: Long, locale: "fr", output: "0 seconde"},
+		{seconds: 7320, style: Long, locale: "fr", output: "2 heures 2 minutes"},
+		{seconds: 5400, style: Short, locale: "fr", output: "1h 30min"},
+		{seconds: 0, style: Long, locale: "de", output: "0 Sekunden"},
+		{seconds: 3660, style: Long, locale: "de", output: "1 Stunde 1 Minute"},
+		{seconds: 214200, style: Short, locale: "de", output: "1W 2T 3Std 30Min"},
+		{seconds: 64800, style: Long, locale: "es", output: "2 días 2 horas"},
+		{seconds: 214200, style: Short, locale: "unknown", output: "1w 2d 3h 30m"},
+	} {
+		if output := GitLab.Format(test.seconds, test.style, test.locale); output != test.output {
+			t.Errorf("Format(%d, %q) = %q, want %q", test.seconds, test.locale, output, test.output)
+		}
+	}
+}
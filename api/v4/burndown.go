@@ -0,0 +1,93 @@
+package apiv4
+
+import (
+	"context"
+
+	"../times"
+
+	"../../db"
+)
+
+type BurndownDay struct {
+	Date                     string
+	TimeSpent                int
+	CumulativeTimeSpent      int
+	RemainingEstimate        int
+	HumanTimeSpent           string
+	HumanCumulativeTimeSpent string
+	HumanRemainingEstimate   string
+}
+
+type Burndown struct {
+	MilestoneID       int `json:",omitempty"`
+	IterationID       int `json:",omitempty"`
+	Title             string
+	StartDate         string
+	DueDate           string
+	TimeEstimate      int
+	HumanTimeEstimate string
+	Days              []BurndownDay
+}
+
+func (a *ApiAPI) GetProjectMilestoneBurndown(ctx context.Context, parameters map[string]string, options map[string][]string) (Burndown, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Burndown{}, nil, err
+	}
+	dbBurndown, err := dbAPI.GetProjectMilestoneBurndown(parameters["projectID"], parameters["milestoneID"], options)
+	if err != nil {
+		return Burndown{}, nil, err
+	}
+	return a.prepareBurndown(ctx, dbBurndown, options), map[string]string{}, nil
+}
+
+func (a *ApiAPI) GetGroupMilestoneBurndown(ctx context.Context, parameters map[string]string, options map[string][]string) (Burndown, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Burndown{}, nil, err
+	}
+	dbBurndown, err := dbAPI.GetGroupMilestoneBurndown(parameters["groupID"], parameters["milestoneID"], options)
+	if err != nil {
+		return Burndown{}, nil, err
+	}
+	return a.prepareBurndown(ctx, dbBurndown, options), map[string]string{}, nil
+}
+
+func (a *ApiAPI) GetGroupIterationBurndown(ctx context.Context, parameters map[string]string, options map[string][]string) (Burndown, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Burndown{}, nil, err
+	}
+	dbBurndown, err := dbAPI.GetGroupIterationBurndown(parameters["groupID"], parameters["iterationID"], options)
+	if err != nil {
+		return Burndown{}, nil, err
+	}
+	burndown := a.prepareBurndown(ctx, dbBurndown, options)
+	burndown.MilestoneID, burndown.IterationID = 0, dbBurndown.Timebox.ID
+	return burndown, map[string]string{}, nil
+}
+
+func (a *ApiAPI) prepareBurndown(ctx context.Context, dbBurndown db.Burndown, options map[string][]string) Burndown {
+	l := locale(ctx, options)
+	burndown := Burndown{
+		MilestoneID:       dbBurndown.Timebox.ID,
+		Title:             dbBurndown.Timebox.Title,
+		StartDate:         dbBurndown.Timebox.StartDate,
+		DueDate:           dbBurndown.Timebox.DueDate,
+		TimeEstimate:      dbBurndown.TimeEstimate,
+		HumanTimeEstimate: a.Api.Durations.Format(int64(dbBurndown.TimeEstimate), times.Short, l),
+		Days:              []BurndownDay{},
+	}
+	for _, dbDay := range dbBurndown.Days {
+		burndown.Days = append(burndown.Days, BurndownDay{
+			Date:                     dbDay.Date,
+			TimeSpent:                dbDay.TimeSpent,
+			CumulativeTimeSpent:      dbDay.CumulativeTimeSpent,
+			RemainingEstimate:        dbDay.RemainingEstimate,
+			HumanTimeSpent:           a.Api.Durations.Format(int64(dbDay.TimeSpent), times.Short, l),
+			HumanCumulativeTimeSpent: a.Api.Durations.Format(int64(dbDay.CumulativeTimeSpent), times.Short, l),
+			HumanRemainingEstimate:   a.Api.Durations.Format(int64(dbDay.RemainingEstimate), times.Short, l),
+		})
+	}
+	return burndown
+}
@@ -0,0 +1,68 @@
+package apiv4
+
+import (
+	"strconv"
+)
+
+func (timelogs Timelogs) ExportHeader() []string {
+	return []string{"Date", "User", "Project", "Reference", "Title", "Seconds", "Duration"}
+}
+
+func (timelogs Timelogs) ExportRows() [][]interface{} {
+	rows := [][]interface{}{}
+	for _, timelog := range timelogs {
+		issuable := timelog.Issue
+		if issuable == nil {
+			issuable = timelog.MergeRequest
+		}
+		if issuable == nil {
+			issuable = &Issuable{ProjectPath: strconv.Itoa(timelog.ProjectID)}
+		}
+		rows = append(rows, []interface{}{
+			timelog.spentDate,
+			timelog.Author.Username,
+			issuable.ProjectPath,
+			issuable.Reference,
+			issuable.Title,
+			timelog.TimeSpent,
+			timelog.HumanTimeSpent,
+		})
+	}
+	return rows
+}
+
+func (summaries TimelogSummaries) ExportHeader() []string {
+	return []string{"Group", "Key", "Seconds", "Count", "Duration"}
+}
+
+func (summaries TimelogSummaries) ExportRows() [][]interface{} {
+	rows := [][]interface{}{}
+	for _, summary := range summaries {
+		rows = append(rows, []interface{}{summary.GroupBy, summary.Key, summary.TimeSpent, summary.Count, summary.HumanTimeSpent})
+	}
+	return rows
+}
+
+func (trackings TimeTrackings) ExportHeader() []string {
+	return []string{"Reference", "Title", "State", "Estimate", "Spent", "Remaining", "Over budget"}
+}
+
+func (trackings TimeTrackings) ExportRows() [][]interface{} {
+	rows := [][]interface{}{}
+	for _, tracking := range trackings {
+		rows = append(rows, []interface{}{tracking.Reference, tracking.Title, tracking.State, tracking.TimeEstimate, tracking.TotalTimeSpent, tracking.RemainingTime, strconv.FormatBool(tracking.OverBudget)})
+	}
+	return rows
+}
+
+func (burndown Burndown) ExportHeader() []string {
+	return []string{"Date", "Spent", "Cumulative spent", "Remaining estimate"}
+}
+
+func (burndown Burndown) ExportRows() [][]interface{} {
+	rows := [][]interface{}{}
+	for _, day := range burndown.Days {
+		rows = append(rows, []interface{}{day.Date, day.TimeSpent, day.CumulativeTimeSpent, day.RemainingEstimate})
+	}
+	return rows
+}
@@ -0,0 +1,78 @@
+package apiv4
+
+import (
+	"strconv"
+
+	"../../db"
+)
+
+type Milestone struct {
+	ID    int
+	IID   int
+	Title string
+}
+
+type Issuable struct {
+	ID          int
+	IID         int
+	ProjectID   int
+	ProjectPath string
+	Reference   string
+	Title       string
+	State       string
+	WebURL      string
+	Labels      []string
+	Milestone   *Milestone
+}
+
+func withIssuable(options map[string][]string) bool {
+	return len(options["with_issuable"]) > 0 && options["with_issuable"][0] == "true"
+}
+
+func (a *ApiAPI) loadIssuables(dbTimelogs db.Timelogs) (map[int]Issuable, map[int]Issuable, error) {
+	issueIDs := []int{}
+	mergeRequestIDs := []int{}
+	for _, dbTimelog := range dbTimelogs {
+		if dbTimelog.IssueID != 0 {
+			issueIDs = append(issueIDs, dbTimelog.IssueID)
+		}
+		if dbTimelog.MergeRequestID != 0 {
+			mergeRequestIDs = append(mergeRequestIDs, dbTimelog.MergeRequestID)
+		}
+	}
+	dbIssues, err := a.Api.DbAPI.GetIssuesByIDs(issueIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbMergeRequests, err := a.Api.DbAPI.GetMergeRequestsByIDs(mergeRequestIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.prepareIssuables(dbIssues, "issues"), a.prepareIssuables(dbMergeRequests, "merge_requests"), nil
+}
+
+func (a *ApiAPI) prepareIssuables(dbIssuables map[int]db.Issuable, resource string) map[int]Issuable {
+	issuables := map[int]Issuable{}
+	sigil := "#"
+	if resource == "merge_requests" {
+		sigil = "!"
+	}
+	for id, dbIssuable := range dbIssuables {
+		issuable := Issuable{
+			ID:          dbIssuable.ID,
+			IID:         dbIssuable.IID,
+			ProjectID:   dbIssuable.ProjectID,
+			ProjectPath: dbIssuable.FullPath,
+			Reference:   dbIssuable.FullPath + sigil + strconv.Itoa(dbIssuable.IID),
+			Title:       dbIssuable.Title,
+			State:       dbIssuable.State,
+			WebURL:      a.Api.Uri + "/" + dbIssuable.FullPath + "/" + resource + "/" + strconv.Itoa(dbIssuable.IID),
+			Labels:      dbIssuable.Labels,
+		}
+		if dbIssuable.MilestoneID != 0 {
+			issuable.Milestone = &Milestone{ID: dbIssuable.MilestoneID, IID: dbIssuable.MilestoneIID, Title: dbIssuable.MilestoneTitle}
+		}
+		issuables[id] = issuable
+	}
+	return issuables
+}
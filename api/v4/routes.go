@@ -0,0 +1,203 @@
+package apiv4
+
+import (
+	"context"
+	"net/url"
+
+	"../../router"
+)
+
+func (a *ApiAPI) Routes() []router.Route {
+	return []router.Route{
+		{
+			Name:    "GetTimelogs",
+			Path:    "/api/v4/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetTimelogs),
+		},
+		{
+			Name:    "GetTimelogsSummary",
+			Path:    "/api/v4/time_logs/summary",
+			Method:  "GET",
+			Auth:    true,
+			Handler: summariesHandler(a.GetTimelogsSummary),
+		},
+		{
+			Name:    "GetIssueTimelogs",
+			Path:    "/api/v4/projects/{projectID}/issues/{issueIID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetIssueTimelogs),
+		},
+		{
+			Name:    "GetMergeRequestTimelogs",
+			Path:    "/api/v4/projects/{projectID}/merge_requests/{mergeRequestIID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetMergeRequestTimelogs),
+		},
+		{
+			Name:    "GetUserTimelogs",
+			Path:    "/api/v4/users/{userID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetUserTimelogs),
+		},
+		{
+			Name:    "GetCurrentUserTimelogs",
+			Path:    "/api/v4/user/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetCurrentUserTimelogs),
+		},
+		{
+			Name:    "GetProjectTimelogs",
+			Path:    "/api/v4/projects/{projectID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetProjectTimelogs),
+		},
+		{
+			Name:    "GetProjectTimeTracking",
+			Path:    "/api/v4/projects/{projectID}/time_tracking",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timeTrackingsHandler(a.GetProjectTimeTracking),
+		},
+		{
+			Name:    "GetProjectMilestoneBurndown",
+			Path:    "/api/v4/projects/{projectID}/milestones/{milestoneID}/time_burndown",
+			Method:  "GET",
+			Auth:    true,
+			Handler: burndownHandler(a.GetProjectMilestoneBurndown),
+		},
+		{
+			Name:    "GetUserTimelogsByProject",
+			Path:    "/api/v4/projects/{projectID}/users/{userID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetUserTimelogsByProject),
+		},
+		{
+			Name:    "GetUserTimelogsByProjectAndIssue",
+			Path:    "/api/v4/projects/{projectID}/issues/{issueIID}/users/{userID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetUserTimelogsByProjectAndIssue),
+		},
+		{
+			Name:    "GetUserTimelogsByProjectAndMergeRequest",
+			Path:    "/api/v4/projects/{projectID}/merge_requests/{mergeRequestIID}/users/{userID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetUserTimelogsByProjectAndMergeRequest),
+		},
+		{
+			Name:    "GetGroupTimelogs",
+			Path:    "/api/v4/groups/{groupID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetGroupTimelogs),
+		},
+		{
+			Name:    "GetUserTimelogsByGroup",
+			Path:    "/api/v4/groups/{groupID}/users/{userID}/time_logs",
+			Method:  "GET",
+			Auth:    true,
+			Handler: timelogsHandler(a.GetUserTimelogsByGroup),
+		},
+		{
+			Name:    "GetGroupMilestoneBurndown",
+			Path:    "/api/v4/groups/{groupID}/milestones/{milestoneID}/time_burndown",
+			Method:  "GET",
+			Auth:    true,
+			Handler: burndownHandler(a.GetGroupMilestoneBurndown),
+		},
+		{
+			Name:    "GetGroupIterationBurndown",
+			Path:    "/api/v4/groups/{groupID}/iterations/{iterationID}/time_burndown",
+			Method:  "GET",
+			Auth:    true,
+			Handler: burndownHandler(a.GetGroupIterationBurndown),
+		},
+		{
+			Name:    "CreateIssueTimelog",
+			Path:    "/api/v4/projects/{projectID}/issues/{issueIID}/time_logs",
+			Method:  "POST",
+			Auth:    true,
+			Scope:   "api",
+			Handler: timelogHandler(a.CreateIssueTimelog),
+		},
+		{
+			Name:    "UpdateIssueTimelog",
+			Path:    "/api/v4/projects/{projectID}/issues/{issueIID}/time_logs/{timelogID}",
+			Method:  "PUT",
+			Auth:    true,
+			Scope:   "api",
+			Handler: timelogHandler(a.UpdateIssueTimelog),
+		},
+		{
+			Name:    "DeleteIssueTimelog",
+			Path:    "/api/v4/projects/{projectID}/issues/{issueIID}/time_logs/{timelogID}",
+			Method:  "DELETE",
+			Auth:    true,
+			Scope:   "api",
+			Handler: timelogHandler(a.DeleteIssueTimelog),
+		},
+		{
+			Name:    "CreateMergeRequestTimelog",
+			Path:    "/api/v4/projects/{projectID}/merge_requests/{mergeRequestIID}/time_logs",
+			Method:  "POST",
+			Auth:    true,
+			Scope:   "api",
+			Handler: timelogHandler(a.CreateMergeRequestTimelog),
+		},
+		{
+			Name:    "UpdateMergeRequestTimelog",
+			Path:    "/api/v4/projects/{projectID}/merge_requests/{mergeRequestIID}/time_logs/{timelogID}",
+			Method:  "PUT",
+			Auth:    true,
+			Scope:   "api",
+			Handler: timelogHandler(a.UpdateMergeRequestTimelog),
+		},
+		{
+			Name:    "DeleteMergeRequestTimelog",
+			Path:    "/api/v4/projects/{projectID}/merge_requests/{mergeRequestIID}/time_logs/{timelogID}",
+			Method:  "DELETE",
+			Auth:    true,
+			Scope:   "api",
+			Handler: timelogHandler(a.DeleteMergeRequestTimelog),
+		},
+	}
+}
+
+func timelogsHandler(h func(context.Context, map[string]string, map[string][]string) (Timelogs, map[string]string, error)) router.HandlerFunc {
+	return func(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error) {
+		return h(ctx, vars, query)
+	}
+}
+
+func timelogHandler(h func(context.Context, map[string]string, map[string][]string) (Timelog, map[string]string, error)) router.HandlerFunc {
+	return func(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error) {
+		return h(ctx, vars, query)
+	}
+}
+
+func summariesHandler(h func(context.Context, map[string]string, map[string][]string) (TimelogSummaries, map[string]string, error)) router.HandlerFunc {
+	return func(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error) {
+		return h(ctx, vars, query)
+	}
+}
+
+func timeTrackingsHandler(h func(context.Context, map[string]string, map[string][]string) (TimeTrackings, map[string]string, error)) router.HandlerFunc {
+	return func(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error) {
+		return h(ctx, vars, query)
+	}
+}
+
+func burndownHandler(h func(context.Context, map[string]string, map[string][]string) (Burndown, map[string]string, error)) router.HandlerFunc {
+	return func(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error) {
+		return h(ctx, vars, query)
+	}
+}
@@ -0,0 +1,44 @@
+package apiv4
+
+import (
+	"context"
+
+	"../times"
+)
+
+type TimelogSummary struct {
+	GroupBy        string
+	Key            string
+	TimeSpent      int
+	Count          int
+	HumanTimeSpent string
+}
+
+type TimelogSummaries []TimelogSummary
+
+func (a *ApiAPI) GetTimelogsSummary(ctx context.Context, parameters map[string]string, options map[string][]string) (TimelogSummaries, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	groupBy := "user"
+	if len(options["group_by"]) > 0 {
+		groupBy = options["group_by"][0]
+	}
+	dbSummaries, err := dbAPI.GetTimelogsSummary(groupBy, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	summaries := TimelogSummaries{}
+	l := locale(ctx, options)
+	for _, dbSummary := range dbSummaries {
+		summaries = append(summaries, TimelogSummary{
+			GroupBy:        groupBy,
+			Key:            dbSummary.Key,
+			TimeSpent:      dbSummary.TimeSpent,
+			Count:          dbSummary.Count,
+			HumanTimeSpent: a.Api.Durations.Format(int64(dbSummary.TimeSpent), times.Short, l),
+		})
+	}
+	return summaries, map[string]string{}, nil
+}
@@ -1,11 +1,16 @@
 package apiv4
 
 import (
+	"context"
+	"math"
+	"strconv"
 	"time"
 
 	"../times"
 
+	"../../apierror"
 	"../../db"
+	"../../router"
 )
 
 type Timelog struct {
@@ -23,94 +28,382 @@ type Timelog struct {
 		CreatedAt time.Time
 	}
 	CreatedAt      time.Time
+	SpentAt        time.Time
 	TimeSpent      int
 	HumanTimeSpent string
+	Issue          *Issuable `json:",omitempty"`
+	MergeRequest   *Issuable `json:",omitempty"`
+	spentDate      string
 }
 
 type Timelogs []Timelog
 
-func (a *ApiAPI) GetTimelogs(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogs(options)
+func (a *ApiAPI) GetTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	dbTimelogs, metadata, err := dbAPI.GetTimelogs(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	if err != nil {
 		return nil, nil, err
 	}
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) GetIssueTimelogs(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogsByIssue(parameters["projectID"], parameters["issueIID"], options)
+func (a *ApiAPI) GetIssueTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByIssue(parameters["projectID"], parameters["issueIID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
+	return timelogs, metadata, err
+}
+
+func (a *ApiAPI) GetMergeRequestTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByMergeRequest(parameters["projectID"], parameters["mergeRequestIID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
+	return timelogs, metadata, err
+}
+
+func (a *ApiAPI) GetUserTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByUser(parameters["userID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
+	return timelogs, metadata, err
+}
+
+func (a *ApiAPI) GetCurrentUserTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	uID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	parameters["userID"] = strconv.Itoa(uID)
+	return a.GetUserTimelogs(ctx, parameters, options)
+}
+
+func (a *ApiAPI) GetProjectTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByProject(parameters["projectID"], options)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) GetMergeRequestTimelogs(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogsByMergeRequest(parameters["projectID"], parameters["mergeRequestIID"], options)
+func (a *ApiAPI) GetUserTimelogsByProject(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByProjectAndUser(parameters["projectID"], parameters["userID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) GetUserTimelogs(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogsByUser(parameters["userID"], options)
+func (a *ApiAPI) GetUserTimelogsByProjectAndIssue(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByProjectAndIssueAndUser(parameters["projectID"], parameters["issueIID"], parameters["userID"], options)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) GetProjectTimelogs(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogsByProject(parameters["projectID"], options)
+func (a *ApiAPI) GetUserTimelogsByProjectAndMergeRequest(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByProjectAndMergeRequestAndUser(parameters["projectID"], parameters["mergeRequestIID"], parameters["userID"], options)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) GetUserTimelogsByProject(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogsByProjectAndUser(parameters["projectID"], parameters["userID"], options)
+func (a *ApiAPI) GetGroupTimelogs(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByGroup(parameters["groupID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) GetUserTimelogsByProjectAndIssue(parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
-	dbTimelogs, metadata, err := a.Api.DbAPI.GetTimelogsByProjectAndIssueAndUser(parameters["projectID"], parameters["issueIID"], parameters["userID"], options)
+func (a *ApiAPI) GetUserTimelogsByGroup(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
 	if err != nil {
 		return nil, nil, err
 	}
-	timelogs, err := a.prepareTimelogs(dbTimelogs)
+	dbTimelogs, metadata, err := dbAPI.GetTimelogsByGroupAndUser(parameters["groupID"], parameters["userID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, dbTimelogs, options)
 	return timelogs, metadata, err
 }
 
-func (a *ApiAPI) prepareTimelogs(dbTimelogs db.Timelogs) (Timelogs, error) {
+func (a *ApiAPI) CreateIssueTimelog(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelog, map[string]string, error) {
+	uID, timeSpent, err := a.timelogWriteInputs(ctx, options)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbTimelog, err := dbAPI.CreateTimelogOnIssue(parameters["projectID"], parameters["issueIID"], uID, timeSpent)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return a.prepareTimelog(ctx, dbAPI, dbTimelog, options)
+}
+
+func (a *ApiAPI) UpdateIssueTimelog(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelog, map[string]string, error) {
+	uID, timeSpent, err := a.timelogWriteInputs(ctx, options)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbTimelog, err := dbAPI.UpdateTimelogOnIssue(parameters["projectID"], parameters["issueIID"], parameters["timelogID"], uID, timeSpent)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return a.prepareTimelog(ctx, dbAPI, dbTimelog, options)
+}
+
+func (a *ApiAPI) DeleteIssueTimelog(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelog, map[string]string, error) {
+	uID, err := currentUserID(ctx)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbTimelog, err := dbAPI.DeleteTimelogOnIssue(parameters["projectID"], parameters["issueIID"], parameters["timelogID"], uID)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return a.prepareTimelog(ctx, dbAPI, dbTimelog, options)
+}
+
+func (a *ApiAPI) CreateMergeRequestTimelog(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelog, map[string]string, error) {
+	uID, timeSpent, err := a.timelogWriteInputs(ctx, options)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbTimelog, err := dbAPI.CreateTimelogOnMergeRequest(parameters["projectID"], parameters["mergeRequestIID"], uID, timeSpent)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return a.prepareTimelog(ctx, dbAPI, dbTimelog, options)
+}
+
+func (a *ApiAPI) UpdateMergeRequestTimelog(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelog, map[string]string, error) {
+	uID, timeSpent, err := a.timelogWriteInputs(ctx, options)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbTimelog, err := dbAPI.UpdateTimelogOnMergeRequest(parameters["projectID"], parameters["mergeRequestIID"], parameters["timelogID"], uID, timeSpent)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return a.prepareTimelog(ctx, dbAPI, dbTimelog, options)
+}
+
+func (a *ApiAPI) DeleteMergeRequestTimelog(ctx context.Context, parameters map[string]string, options map[string][]string) (Timelog, map[string]string, error) {
+	uID, err := currentUserID(ctx)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	dbTimelog, err := dbAPI.DeleteTimelogOnMergeRequest(parameters["projectID"], parameters["mergeRequestIID"], parameters["timelogID"], uID)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return a.prepareTimelog(ctx, dbAPI, dbTimelog, options)
+}
+
+// pathIDs are the numeric path parameters, with the resource reported when
+// they are malformed.
+var pathIDs = map[string]string{
+	"issueIID":        "Issue",
+	"iterationID":     "Iteration",
+	"mergeRequestIID": "Merge Request",
+	"milestoneID":     "Milestone",
+	"timelogID":       "Timelog",
+}
+
+func (a *ApiAPI) dbAPI(ctx context.Context, parameters map[string]string) (*db.DbAPI, error) {
+	uID, err := currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dbAPI, err := a.Api.DbAPI.ForUser(uID)
+	if err != nil {
+		return nil, err
+	}
+	if projectID, exists := parameters["projectID"]; exists {
+		parameters["projectID"], err = dbAPI.ResolveProject(projectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if groupID, exists := parameters["groupID"]; exists {
+		parameters["groupID"], err = dbAPI.ResolveGroup(groupID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if userID, exists := parameters["userID"]; exists {
+		parameters["userID"], err = dbAPI.ResolveUser(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for key, resource := range pathIDs {
+		if id, exists := parameters[key]; exists {
+			if n, err := strconv.ParseInt(id, 10, 32); err != nil || n <= 0 {
+				return nil, apierror.NotFound(resource)
+			}
+		}
+	}
+	return dbAPI, nil
+}
+
+func currentUserID(ctx context.Context) (int, error) {
+	uID, exists := router.UserID(ctx)
+	if !exists {
+		return 0, apierror.Unauthorized()
+	}
+	return uID, nil
+}
+
+func locale(ctx context.Context, options map[string][]string) string {
+	if len(options["locale"]) > 0 && options["locale"][0] != "" {
+		return times.MatchLocale(options["locale"][0])
+	}
+	return times.MatchLocale(router.AcceptLanguage(ctx))
+}
+
+func (a *ApiAPI) timelogWriteInputs(ctx context.Context, options map[string][]string) (int, int, error) {
+	uID, err := currentUserID(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(options["duration"]) == 0 || options["duration"][0] == "" {
+		return 0, 0, apierror.BadRequest("duration is missing")
+	}
+	timeSpent, err := a.Api.Durations.Parse(options["duration"][0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if timeSpent == 0 {
+		return 0, 0, apierror.BadRequest("duration must not be zero")
+	}
+	if timeSpent > math.MaxInt32 || timeSpent < -math.MaxInt32 {
+		return 0, 0, apierror.BadRequest("duration is too long")
+	}
+	return uID, int(timeSpent), nil
+}
+
+func (a *ApiAPI) prepareTimelog(ctx context.Context, dbAPI *db.DbAPI, dbTimelog db.Timelog, options map[string][]string) (Timelog, map[string]string, error) {
+	timelogs, err := a.prepareTimelogs(ctx, dbAPI, db.Timelogs{dbTimelog}, options)
+	if err != nil {
+		return Timelog{}, nil, err
+	}
+	return timelogs[0], map[string]string{}, nil
+}
+
+func (a *ApiAPI) prepareTimelogs(ctx context.Context, dbAPI *db.DbAPI, dbTimelogs db.Timelogs, options map[string][]string) (Timelogs, error) {
 	var err error
+	var issues, mergeRequests map[int]Issuable
 	timelogs := Timelogs{}
-	for _, dbTimelog := range dbTimelogs {
-		timelog := Timelog{ID: dbTimelog.Id, ProjectID: dbTimelog.ProjectID, IssueID: dbTimelog.IssueID, MergeRequestID: dbTimelog.MergeRequestID, CreatedAt: dbTimelog.CreatedAt, TimeSpent: dbTimelog.TimeSpent}
-		author, err := a.Api.DbAPI.GetUserByID(dbTimelog.UserID)
+	if withIssuable(options) {
+		issues, mergeRequests, err = a.loadIssuables(dbTimelogs)
 		if err != nil {
 			return timelogs, err
 		}
+	}
+	userIDs := []int{}
+	for _, dbTimelog := range dbTimelogs {
+		userIDs = append(userIDs, dbTimelog.UserID)
+	}
+	authors, err := a.Api.DbAPI.GetUsersByIDs(userIDs)
+	if err != nil {
+		return timelogs, err
+	}
+	l := locale(ctx, options)
+	loc := dbAPI.Location(options)
+	for _, dbTimelog := range dbTimelogs {
+		timelog := Timelog{ID: dbTimelog.Id, ProjectID: dbTimelog.ProjectID, IssueID: dbTimelog.IssueID, IssueIID: dbTimelog.IssueIID, MergeRequestID: dbTimelog.MergeRequestID, CreatedAt: dbTimelog.CreatedAt, SpentAt: dbTimelog.SpentAt, TimeSpent: dbTimelog.TimeSpent}
+		author := authors[dbTimelog.UserID]
 		timelog.Author.ID = dbTimelog.UserID
 		timelog.Author.Username = author.Username
 		timelog.Author.Email = author.Email
 		timelog.Author.Name = author.Name
 		timelog.Author.State = author.State
 		timelog.Author.CreatedAt = author.CreatedAt
-		timelog.HumanTimeSpent = times.HumanTimeConversion(int64(timelog.TimeSpent), "short", "hour", " ")
+		timelog.spentDate = dbTimelog.SpentAt.In(loc).Format("2006-01-02")
+		timelog.HumanTimeSpent = a.Api.Durations.Format(int64(timelog.TimeSpent), times.Short, l)
+		if issue, exists := issues[dbTimelog.IssueID]; exists {
+			timelog.Issue = &issue
+		}
+		if mergeRequest, exists := mergeRequests[dbTimelog.MergeRequestID]; exists {
+			timelog.MergeRequest = &mergeRequest
+		}
 		timelogs = append(timelogs, timelog)
 	}
 	return timelogs, err
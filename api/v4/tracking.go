@@ -0,0 +1,68 @@
+package apiv4
+
+import (
+	"context"
+	"strconv"
+
+	"../times"
+)
+
+type TimeTracking struct {
+	ID                  int
+	IID                 int
+	ProjectID           int
+	TargetType          string
+	Title               string
+	State               string
+	Reference           string
+	WebURL              string
+	TimeEstimate        int
+	TotalTimeSpent      int
+	RemainingTime       int
+	OverBudget          bool
+	HumanTimeEstimate   string
+	HumanTotalTimeSpent string
+	HumanRemainingTime  string
+}
+
+type TimeTrackings []TimeTracking
+
+func (a *ApiAPI) GetProjectTimeTracking(ctx context.Context, parameters map[string]string, options map[string][]string) (TimeTrackings, map[string]string, error) {
+	dbAPI, err := a.dbAPI(ctx, parameters)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbTrackings, err := dbAPI.GetTimeTrackingByProject(parameters["projectID"], options)
+	if err != nil {
+		return nil, nil, err
+	}
+	trackings := TimeTrackings{}
+	l := locale(ctx, options)
+	for _, dbTracking := range dbTrackings {
+		resource, sigil := "issues", "#"
+		if dbTracking.TargetType == "merge_request" {
+			resource, sigil = "merge_requests", "!"
+		}
+		tracking := TimeTracking{
+			ID:             dbTracking.ID,
+			IID:            dbTracking.IID,
+			ProjectID:      dbTracking.ProjectID,
+			TargetType:     dbTracking.TargetType,
+			Title:          dbTracking.Title,
+			State:          dbTracking.State,
+			Reference:      dbTracking.FullPath + sigil + strconv.Itoa(dbTracking.IID),
+			WebURL:         a.Api.Uri + "/" + dbTracking.FullPath + "/" + resource + "/" + strconv.Itoa(dbTracking.IID),
+			TimeEstimate:   dbTracking.TimeEstimate,
+			TotalTimeSpent: dbTracking.TotalTimeSpent,
+			OverBudget:     dbTracking.TimeEstimate > 0 && dbTracking.TotalTimeSpent > dbTracking.TimeEstimate,
+		}
+		if dbTracking.TimeEstimate > dbTracking.TotalTimeSpent {
+			tracking.RemainingTime = dbTracking.TimeEstimate - dbTracking.TotalTimeSpent
+		}
+		tracking.HumanTimeEstimate = a.Api.Durations.Format(int64(tracking.TimeEstimate), times.Short, l)
+		tracking.HumanTotalTimeSpent = a.Api.Durations.Format(int64(tracking.TotalTimeSpent), times.Short, l)
+		tracking.HumanRemainingTime = a.Api.Durations.Format(int64(tracking.RemainingTime), times.Short, l)
+		trackings = append(trackings, tracking)
+	}
+	return trackings, map[string]string{}, nil
+}
@@ -0,0 +1,49 @@
+package apierror
+
+import (
+	"net/http"
+)
+
+type Error struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func BadRequest(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Message: message}
+}
+
+func Unauthorized() *Error {
+	return &Error{Status: http.StatusUnauthorized, Message: "401 Unauthorized"}
+}
+
+func Forbidden() *Error {
+	return &Error{Status: http.StatusForbidden, Message: "403 Forbidden"}
+}
+
+func NotFound(resource string) *Error {
+	return &Error{Status: http.StatusNotFound, Message: "404 " + resource + " Not Found"}
+}
+
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Message: "500 Internal Server Error", Err: err}
+}
+
+func Response(err error) (int, map[string]string) {
+	e, ok := err.(*Error)
+	if !ok || e.Status >= http.StatusInternalServerError {
+		return http.StatusInternalServerError, map[string]string{"message": "500 Internal Server Error"}
+	}
+	if e.Status == http.StatusBadRequest {
+		return e.Status, map[string]string{"error": e.Message}
+	}
+	return e.Status, map[string]string{"message": e.Message}
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+const envPrefix = "GLAE_"
+
+type Config struct {
+	BindAddress       string        `yaml:"bindAddress" usage:"The address (incl. port) to bind"`
+	Uri               string        `yaml:"uri" usage:"The gitlab instance uri"`
+	GitlabSocketPath  string        `yaml:"gitlabSocketPath" usage:"The gitlab workhorse socket path"`
+	TLSCertificate    string        `yaml:"tlsCertificate" usage:"The TLS certificate file"`
+	TLSKey            string        `yaml:"tlsKey" usage:"The TLS key file"`
+	DbSocketPath      string        `yaml:"dbSocketPath" usage:"The database socket path"`
+	DbHost            string        `yaml:"dbHost" usage:"The database host (overrides dbSocketPath)"`
+	DbPort            int           `yaml:"dbPort" usage:"The database port"`
+	DbName            string        `yaml:"dbName" usage:"The database name"`
+	DbUser            string        `yaml:"dbUser" usage:"The database user"`
+	DbPassword        string        `yaml:"dbPassword"`
+	DbPasswordFile    string        `yaml:"dbPasswordFile" usage:"The file containing the database password (defaults to the PGPASSWORD environment variable)"`
+	DbSSLMode         string        `yaml:"dbSSLMode" usage:"The database SSL mode (disable, require, verify-ca or verify-full)"`
+	DbSSLRootCert     string        `yaml:"dbSSLRootCert" usage:"The database CA certificate file"`
+	DbSSLCert         string        `yaml:"dbSSLCert" usage:"The database client certificate file"`
+	DbSSLKey          string        `yaml:"dbSSLKey" usage:"The database client key file"`
+	DbDSN             string        `yaml:"dbDSN" usage:"The full database DSN or URL (overrides all other database connection settings)"`
+	DbReplicaDSN      []string      `yaml:"dbReplicaDSN" usage:"A read replica database DSN or URL (repeatable)"`
+	DbMaxOpenConns    int           `yaml:"dbMaxOpenConns" usage:"The maximum number of open database connections (0 means unlimited)"`
+	DbMaxIdleConns    int           `yaml:"dbMaxIdleConns" usage:"The maximum number of idle database connections"`
+	DbConnMaxLifetime time.Duration `yaml:"dbConnMaxLifetime" usage:"The maximum lifetime of a database connection (0 means unlimited)"`
+	Routes            []string      `yaml:"routes" usage:"An extension route to enable, by handler name (repeatable, defaults to all)"`
+	HoursPerDay       int           `yaml:"hoursPerDay" usage:"The number of hours in a day of tracked time (defaults to 8, as in GitLab)"`
+	DaysPerWeek       int           `yaml:"daysPerWeek" usage:"The number of days in a week of tracked time (defaults to 5, as in GitLab)"`
+	LimitToHours      bool          `yaml:"timeTrackingLimitToHours" usage:"Display tracked time in hours only, like GitLab's time_tracking_limit_to_hours setting"`
+	LocalesPath       string        `yaml:"localesPath" usage:"A directory of <locale>.yml duration translation tables, adding to or overriding the built-in ones"`
+}
+
+type fieldValue struct {
+	v reflect.Value
+}
+
+func (f fieldValue) String() string {
+	if !f.v.IsValid() {
+		return ""
+	}
+	if f.v.Kind() == reflect.Slice {
+		return strings.Join(f.v.Interface().([]string), ",")
+	}
+	return fmtValue(f.v)
+}
+
+func (f fieldValue) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
+
+func (f fieldValue) Set(s string) error {
+	if f.v.Kind() == reflect.Slice {
+		f.v.Set(reflect.Append(f.v, reflect.ValueOf(s)))
+		return nil
+	}
+	return setValue(f.v, s)
+}
+
+func Parse(fs *flag.FlagSet, args []string) (Config, error) {
+	var cfg Config
+	var flags Config
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "The YAML configuration file")
+	fields := reflect.ValueOf(&flags).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Type().Field(i)
+		if usage := field.Tag.Get("usage"); usage != "" {
+			fs.Var(fieldValue{fields.Field(i)}, field.Tag.Get("yaml"), usage)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if *configPath != "" {
+		content, err := ioutil.ReadFile(*configPath)
+		if err != nil {
+			return cfg, err
+		}
+		if err = yaml.UnmarshalStrict(content, &cfg); err != nil {
+			return cfg, errors.New("invalid configuration file " + *configPath + ": " + err.Error())
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	values := reflect.ValueOf(&cfg).Elem()
+	fs.Visit(func(f *flag.Flag) {
+		for i := 0; i < fields.NumField(); i++ {
+			if fields.Type().Field(i).Tag.Get("yaml") == f.Name {
+				values.Field(i).Set(fields.Field(i))
+			}
+		}
+	})
+	if cfg.DbPasswordFile != "" {
+		p, err := ioutil.ReadFile(cfg.DbPasswordFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.DbPassword = strings.TrimSpace(string(p))
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) applyEnv() error {
+	values := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < values.NumField(); i++ {
+		name := EnvName(values.Type().Field(i).Tag.Get("yaml"))
+		env, exists := os.LookupEnv(name)
+		if !exists {
+			continue
+		}
+		if values.Field(i).Kind() == reflect.Slice {
+			values.Field(i).Set(reflect.ValueOf(strings.Split(env, ",")))
+			continue
+		}
+		if err := setValue(values.Field(i), env); err != nil {
+			return errors.New("invalid value for " + name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func (cfg Config) Validate(routes []string) error {
+	messages := []string{}
+	u, err := url.Parse(cfg.Uri)
+	if cfg.Uri == "" {
+		messages = append(messages, "uri is required")
+	} else if err != nil || u.Scheme == "" || u.Host == "" {
+		messages = append(messages, "uri must be an absolute URL such as https://gitlab.example.com")
+	}
+	if cfg.GitlabSocketPath == "" {
+		messages = append(messages, "gitlabSocketPath is required")
+	}
+	if (cfg.TLSCertificate == "") != (cfg.TLSKey == "") {
+		messages = append(messages, "tlsCertificate and tlsKey must be set together")
+	}
+	if cfg.DbDSN == "" && cfg.DbName == "" {
+		messages = append(messages, "dbName (or dbDSN) is required")
+	}
+	if cfg.DbDSN == "" && cfg.DbHost == "" && cfg.DbSocketPath == "" {
+		messages = append(messages, "dbHost or dbSocketPath (or dbDSN) is required")
+	}
+	if cfg.DbPort < 0 || cfg.DbPort > 65535 {
+		messages = append(messages, "dbPort must be between 0 and 65535")
+	}
+	if cfg.HoursPerDay < 0 || cfg.HoursPerDay > 24 {
+		messages = append(messages, "hoursPerDay must be between 1 and 24")
+	}
+	if cfg.DaysPerWeek < 0 || cfg.DaysPerWeek > 7 {
+		messages = append(messages, "daysPerWeek must be between 1 and 7")
+	}
+	for _, route := range cfg.Routes {
+		known := false
+		for _, r := range routes {
+			known = known || r == route
+		}
+		if !known {
+			messages = append(messages, "unknown route "+route+" (allowed: "+strings.Join(routes, ", ")+")")
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+func (cfg Config) RouteEnabled(route string) bool {
+	if len(cfg.Routes) == 0 {
+		return true
+	}
+	for _, r := range cfg.Routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+func EnvName(key string) string {
+	name := []rune{}
+	runes := []rune(key)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			name = append(name, '_')
+		}
+		name = append(name, unicode.ToUpper(r))
+	}
+	return envPrefix + string(name)
+}
+
+func fmtValue(v reflect.Value) string {
+	switch v.Interface().(type) {
+	case time.Duration:
+		return v.Interface().(time.Duration).String()
+	case int:
+		return strconv.Itoa(int(v.Int()))
+	case bool:
+		return strconv.FormatBool(v.Bool())
+	}
+	return v.String()
+}
+
+func setValue(v reflect.Value, s string) error {
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(d))
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		v.SetString(s)
+	}
+	return nil
+}
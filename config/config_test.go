@@ -0,0 +1,135 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	for _, test := range []struct {
+		key  string
+		name string
+	}{
+		{key: "uri", name: "GLAE_URI"},
+		{key: "bindAddress", name: "GLAE_BIND_ADDRESS"},
+		{key: "tlsCertificate", name: "GLAE_TLS_CERTIFICATE"},
+		{key: "dbDSN", name: "GLAE_DB_DSN"},
+		{key: "dbSSLMode", name: "GLAE_DB_SSL_MODE"},
+		{key: "dbSSLRootCert", name: "GLAE_DB_SSL_ROOT_CERT"},
+		{key: "dbReplicaDSN", name: "GLAE_DB_REPLICA_DSN"},
+		{key: "timeTrackingLimitToHours", name: "GLAE_TIME_TRACKING_LIMIT_TO_HOURS"},
+	} {
+		if name := EnvName(test.key); name != test.name {
+			t.Errorf("EnvName(%q) = %q, want %q", test.key, name, test.name)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.yml")
+	content := "uri: https://file.example.com\ndbPort: 5432\ndbName: file\nroutes: [GetTimelogs]\ntimeTrackingLimitToHours: true\n"
+	if err = ioutil.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passwordPath := filepath.Join(dir, "password")
+	if err = ioutil.WriteFile(passwordPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		check   func(Config) interface{}
+		want    interface{}
+		invalid bool
+	}{
+		{
+			name: "file",
+			args: []string{"-config", configPath},
+			check: func(cfg Config) interface{} {
+				return []interface{}{cfg.Uri, cfg.DbPort, cfg.DbName, cfg.Routes, cfg.LimitToHours}
+			},
+			want: []interface{}{"https://file.example.com", 5432, "file", []string{"GetTimelogs"}, true},
+		},
+		{
+			name:  "config path from the environment",
+			env:   map[string]string{"GLAE_CONFIG": configPath},
+			check: func(cfg Config) interface{} { return cfg.Uri },
+			want:  "https://file.example.com",
+		},
+		{
+			name:  "environment over file",
+			args:  []string{"-config", configPath},
+			env:   map[string]string{"GLAE_DB_NAME": "env", "GLAE_ROUTES": "GetTimelogs,GetUserTimelogs", "GLAE_TIME_TRACKING_LIMIT_TO_HOURS": "false"},
+			check: func(cfg Config) interface{} { return []interface{}{cfg.Uri, cfg.DbName, cfg.Routes, cfg.LimitToHours} },
+			want:  []interface{}{"https://file.example.com", "env", []string{"GetTimelogs", "GetUserTimelogs"}, false},
+		},
+		{
+			name:  "flags over environment and file",
+			args:  []string{"-config", configPath, "-dbName", "flag", "-dbPort", "6432", "-routes", "GetTimelogsSummary"},
+			env:   map[string]string{"GLAE_DB_NAME": "env", "GLAE_DB_PORT": "7432"},
+			check: func(cfg Config) interface{} { return []interface{}{cfg.DbName, cfg.DbPort, cfg.Routes} },
+			want:  []interface{}{"flag", 6432, []string{"GetTimelogsSummary"}},
+		},
+		{
+			name:  "bool and duration flags",
+			args:  []string{"-timeTrackingLimitToHours", "-dbConnMaxLifetime", "5m"},
+			check: func(cfg Config) interface{} { return []interface{}{cfg.LimitToHours, cfg.DbConnMaxLifetime} },
+			want:  []interface{}{true, 5 * time.Minute},
+		},
+		{
+			name:  "password file",
+			args:  []string{"-dbPasswordFile", passwordPath},
+			check: func(cfg Config) interface{} { return cfg.DbPassword },
+			want:  "secret",
+		},
+		{
+			name:    "invalid environment value",
+			env:     map[string]string{"GLAE_DB_PORT": "postgres"},
+			invalid: true,
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-dbPassword", "secret"},
+			invalid: true,
+		},
+		{
+			name:    "missing file",
+			args:    []string{"-config", filepath.Join(dir, "missing.yml")},
+			invalid: true,
+		},
+	} {
+		for key, value := range test.env {
+			os.Setenv(key, value)
+		}
+		fs := flag.NewFlagSet(test.name, flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		cfg, err := Parse(fs, test.args)
+		for key := range test.env {
+			os.Unsetenv(key)
+		}
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%s: Parse(%v) = nil error, want an error", test.name, test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Parse(%v) = %v", test.name, test.args, err)
+			continue
+		}
+		if got := test.check(cfg); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Parse(%v) = %v, want %v", test.name, test.args, got, test.want)
+		}
+	}
+}
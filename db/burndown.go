@@ -0,0 +1,147 @@
+package db
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+// Timebox is the milestone or iteration of a burn-down.
+type Timebox struct {
+	ID        int
+	Title     string
+	StartDate string
+	DueDate   string
+}
+
+type BurndownDay struct {
+	Date                string
+	TimeSpent           int
+	CumulativeTimeSpent int
+	RemainingEstimate   int
+}
+
+type Burndown struct {
+	Timebox      Timebox
+	TimeEstimate int
+	Days         []BurndownDay
+}
+
+type timebox struct {
+	table       string
+	issueColumn string
+	notFound    string
+}
+
+var milestoneTimebox = timebox{table: "milestones", issueColumn: "milestone_id", notFound: "Milestone"}
+var iterationTimebox = timebox{table: "sprints", issueColumn: "sprint_id", notFound: "Iteration"}
+
+func (db *DbAPI) GetProjectMilestoneBurndown(pID string, mID string, options map[string][]string) (Burndown, error) {
+	return db.getBurndown(milestoneTimebox, dbr.Eq("milestones.project_id", pID), mID, options)
+}
+
+func (db *DbAPI) GetGroupMilestoneBurndown(gID string, mID string, options map[string][]string) (Burndown, error) {
+	return db.getBurndown(milestoneTimebox, db.restrictGroup(dbr.Eq("milestones.group_id", gID), "milestones.group_id"), mID, options)
+}
+
+func (db *DbAPI) GetGroupIterationBurndown(gID string, iID string, options map[string][]string) (Burndown, error) {
+	if !hasIterations {
+		return Burndown{Days: []BurndownDay{}}, apierror.NotFound(iterationTimebox.notFound)
+	}
+	return db.getBurndown(iterationTimebox, db.restrictGroup(dbr.Eq("sprints.group_id", gID), "sprints.group_id"), iID, options)
+}
+
+const maxBurndownDays = 366
+
+// getBurndown sums the time spent per day on the milestone or iteration issues,
+// from its start date (or creation) to its due date (or today). Longer ranges
+// stop today and keep their last maxBurndownDays days. Time spent outside of
+// the range is counted on its first or last day.
+func (db *DbAPI) getBurndown(t timebox, owner dbr.Builder, id string, options map[string][]string) (Burndown, error) {
+	burndown := Burndown{Days: []BurndownDay{}}
+	if err := validateFilters(options); err != nil {
+		return burndown, err
+	}
+	if _, err := strconv.Atoi(id); err != nil {
+		return burndown, apierror.NotFound(t.notFound)
+	}
+	n, err := db.reader().Select(t.table+".id", "COALESCE("+t.table+".title, '') AS title",
+		"to_char(COALESCE("+t.table+".start_date, "+t.table+".created_at::date), 'YYYY-MM-DD') AS start_date",
+		"to_char(COALESCE("+t.table+".due_date, CURRENT_DATE), 'YYYY-MM-DD') AS due_date").
+		From(t.table).
+		Where(dbr.And(dbr.Eq(t.table+".id", id), owner)).
+		Load(&burndown.Timebox)
+	if err != nil {
+		return burndown, err
+	}
+	if n == 0 {
+		return burndown, apierror.NotFound(t.notFound)
+	}
+	_, err = db.reader().Select("COALESCE(SUM(issues.time_estimate), 0)").
+		From("issues").
+		Where(db.restrictIssuable(dbr.Eq("issues."+t.issueColumn, id), issueIssuable)).
+		Load(&burndown.TimeEstimate)
+	if err != nil {
+		return burndown, err
+	}
+	day := "to_char(" + db.localDateColumn("timelogs", options) + ", 'YYYY-MM-DD')"
+	spent := []BurndownDay{}
+	_, err = db.reader().Select(day+" AS date", "SUM(timelogs.time_spent) AS time_spent").
+		From("timelogs").
+		Join("issues", "issues.id = timelogs.issue_id").
+		Where(db.restrictTimelogs(dbr.Eq("issues."+t.issueColumn, id), "issues.project_id")).
+		GroupBy(day).
+		Load(&spent)
+	if err != nil {
+		return burndown, err
+	}
+	start, err := time.Parse("2006-01-02", burndown.Timebox.StartDate)
+	if err != nil {
+		return burndown, err
+	}
+	due, err := time.Parse("2006-01-02", burndown.Timebox.DueDate)
+	if err != nil {
+		return burndown, err
+	}
+	if due.Before(start) {
+		due = start
+	}
+	if due.Sub(start) >= maxBurndownDays*24*time.Hour {
+		now := time.Now().In(db.Location(options))
+		if today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC); due.After(today) {
+			due = today
+		}
+		if due.Before(start) {
+			due = start
+		}
+		if due.Sub(start) >= maxBurndownDays*24*time.Hour {
+			start = due.AddDate(0, 0, 1-maxBurndownDays)
+		}
+	}
+	first, last := start.Format("2006-01-02"), due.Format("2006-01-02")
+	spentByDay := map[string]int{}
+	for _, s := range spent {
+		date := s.Date
+		if date < first {
+			date = first
+		}
+		if date > last {
+			date = last
+		}
+		spentByDay[date] += s.TimeSpent
+	}
+	cumulative := 0
+	for d := start; !d.After(due); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		cumulative += spentByDay[date]
+		remaining := burndown.TimeEstimate - cumulative
+		if remaining < 0 {
+			remaining = 0
+		}
+		burndown.Days = append(burndown.Days, BurndownDay{Date: date, TimeSpent: spentByDay[date], CumulativeTimeSpent: cumulative, RemainingEstimate: remaining})
+	}
+	return burndown, nil
+}
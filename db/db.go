@@ -1,31 +1,91 @@
 package db
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	_ "github.com/lib/pq"
 	"github.com/skilld-labs/dbr"
-	"strconv"
+
+	"../apierror"
 )
 
 type Config struct {
-	SocketPath string
-	Name       string
+	SocketPath      string
+	Host            string
+	Port            int
+	Name            string
+	User            string
+	Password        string
+	SSLMode         string
+	SSLRootCert     string
+	SSLCert         string
+	SSLKey          string
+	DSN             string
+	ReplicaDSNs     []string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
 }
 
 type DbAPI struct {
-	Db *dbr.Session
+	Db             *dbr.Session
+	replicas       *replicaSet
+	viewerID       int
+	viewerAdmin    bool
+	viewerExternal bool
+	viewerTimezone string
 }
 
 func New(cfg Config) (*dbr.Session, error) {
-	c, err := dbr.Open("postgres", "host="+cfg.SocketPath+" dbname="+cfg.Name, nil)
+	c, err := dbr.Open("postgres", dsn(cfg), nil)
 	if err != nil {
 		return &dbr.Session{}, err
 	}
+	c.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		c.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	c.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 	d := c.NewSession(nil)
 	return d, err
 }
 
-func NewDbAPI(d *dbr.Session) *DbAPI {
-	return &DbAPI{Db: d}
+func dsn(cfg Config) string {
+	if cfg.DSN != "" {
+		return cfg.DSN
+	}
+	host := cfg.Host
+	if host == "" {
+		host = cfg.SocketPath
+	}
+	port := ""
+	if cfg.Port > 0 {
+		port = strconv.Itoa(cfg.Port)
+	}
+	parameters := []string{}
+	for _, p := range [][]string{
+		{"host", host},
+		{"port", port},
+		{"dbname", cfg.Name},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	} {
+		if p[1] != "" {
+			parameters = append(parameters, p[0]+"='"+strings.Replace(strings.Replace(p[1], `\`, `\\`, -1), "'", `\'`, -1)+"'")
+		}
+	}
+	return strings.Join(parameters, " ")
+}
+
+func NewDbAPI(d *dbr.Session, replicas ...*dbr.Session) *DbAPI {
+	detectSchema(d)
+	return &DbAPI{Db: d, replicas: newReplicaSet(replicas)}
 }
 
 func paginate(q *dbr.SelectBuilder, options map[string][]string) (*dbr.SelectBuilder, map[string]string, error) {
@@ -39,16 +99,25 @@ func paginate(q *dbr.SelectBuilder, options map[string][]string) (*dbr.SelectBui
 	perPage = 20
 	if len(options["page"]) > 0 {
 		page, err = strconv.ParseUint(options["page"][0], 10, 64)
-		if err != nil {
-			return q, nil, err
+		if err != nil || page == 0 {
+			return q, nil, apierror.BadRequest("page is invalid")
 		}
 	}
 	if len(options["per_page"]) > 0 {
 		perPage, err = strconv.ParseUint(options["per_page"][0], 10, 64)
-		if err != nil {
-			return q, nil, err
+		if err != nil || perPage == 0 {
+			return q, nil, apierror.BadRequest("per_page is invalid")
 		}
 	}
+	if err = validateFilters(options); err != nil {
+		return q, nil, err
+	}
+	if err = validateSort(options); err != nil {
+		return q, nil, err
+	}
+	if isKeyset(options) {
+		return keysetPaginate(q, perPage, options)
+	}
 	countSelect = int(perPage)
 	order := q.SelectStmt.Order
 	column := q.SelectStmt.Column[0]
@@ -76,63 +145,79 @@ func paginate(q *dbr.SelectBuilder, options map[string][]string) (*dbr.SelectBui
 		}
 		metadata["Total"] = strconv.Itoa(countAll)
 		if countSelect > 0 {
-			if countAll == countSelect {
-				metadata["Total-Pages"] = "1"
-			} else if countSelect == 1 {
-				metadata["Total-Pages"] = strconv.Itoa(countAll)
-			} else {
-				metadata["Total-Pages"] = strconv.Itoa(countAll/countSelect + 1)
-			}
+			metadata["Total-Pages"] = strconv.Itoa((countAll + countSelect - 1) / countSelect)
 		}
 	}
 	return q, metadata, err
 }
 
+var orderColumns = []string{"created_at", "updated_at", "spent_at", "time_spent", "id", "user"}
+
 func sort(q *dbr.SelectBuilder, options map[string][]string) *dbr.SelectBuilder {
-	var isAsc bool = false
-	var orderBy string
-	if _, exists := options["sort"]; exists {
-		isAsc = options["sort"][0] == "asc"
+	orderBy, isAsc := sortOptions(options)
+	sortedByID := false
+	for n, key := range orderBy {
+		if column := orderColumn("timelogs", key); column != "" {
+			q = q.OrderDir(column, isAsc[n])
+			sortedByID = sortedByID || key == "id"
+		}
 	}
-	if _, exists := options["order_by"]; exists {
-		orderBy = options["order_by"][0]
-	} else {
-		orderBy = "created_at"
+	if !sortedByID {
+		q = q.OrderDir("timelogs.id", isAsc[len(isAsc)-1])
 	}
-	return q.OrderDir(orderBy, isAsc)
+	return q
 }
 
-func timeframeBuilder(table string, options map[string][]string) dbr.Builder {
-	var timeframeQuery dbr.Builder = nil
-	var sinceQuery dbr.Builder = nil
-	var untilQuery dbr.Builder = nil
-	_, sinceExists := options["since"]
-	_, untilExists := options["until"]
-
-	if sinceExists {
-		sinceQuery = dbr.Gt(table+".created_at", options["since"][0])
-		timeframeQuery = sinceQuery
+func sortOptions(options map[string][]string) ([]string, []bool) {
+	orderBy := []string{dateField(options)}
+	if len(options["order_by"]) > 0 {
+		orderBy = strings.Split(options["order_by"][0], ",")
 	}
-
-	if untilExists {
-		untilQuery = dbr.Lt(table+".created_at", options["until"][0])
-		timeframeQuery = untilQuery
+	directions := []string{"desc"}
+	if len(options["sort"]) > 0 {
+		directions = strings.Split(options["sort"][0], ",")
 	}
-
-	if untilExists && sinceExists {
-		timeframeQuery = dbr.And(sinceQuery, untilQuery)
+	isAsc := []bool{}
+	for n := range orderBy {
+		direction := directions[0]
+		if n < len(directions) {
+			direction = directions[n]
+		}
+		isAsc = append(isAsc, direction == "asc")
 	}
+	return orderBy, isAsc
+}
 
-	return timeframeQuery
+func orderColumn(alias string, key string) string {
+	switch key {
+	case "created_at", "updated_at", "time_spent", "id":
+		return alias + "." + key
+	case "spent_at":
+		return spentAt(alias)
+	case "user":
+		return "(SELECT users.username FROM users WHERE users.id = " + alias + ".user_id)"
+	}
+	return ""
 }
 
-func timeframe(w dbr.Builder, table string, options map[string][]string) dbr.Builder {
-	tf := timeframeBuilder(table, options)
-	if w == nil {
-		return tf
+func validateSort(options map[string][]string) error {
+	if len(options["order_by"]) > 0 {
+		keys := strings.Split(options["order_by"][0], ",")
+		for _, key := range keys {
+			if orderColumn("timelogs", key) == "" {
+				return apierror.BadRequest("order_by does not have a valid value, allowed values are: " + strings.Join(orderColumns, ", "))
+			}
+		}
+		if len(keys) > 1 && isKeyset(options) {
+			return apierror.BadRequest("order_by must be a single value with keyset pagination")
+		}
 	}
-	if tf != nil {
-		w = dbr.And(w, tf)
+	if len(options["sort"]) > 0 {
+		for _, direction := range strings.Split(options["sort"][0], ",") {
+			if direction != "asc" && direction != "desc" {
+				return apierror.BadRequest("sort does not have a valid value, allowed values are: asc, desc")
+			}
+		}
 	}
-	return w
+	return nil
 }
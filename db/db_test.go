@@ -0,0 +1,72 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderColumn(t *testing.T) {
+	hasSpentAt = true
+	defer func() { hasSpentAt = false }()
+	for _, test := range []struct {
+		key    string
+		column string
+	}{
+		{key: "created_at", column: "timelogs.created_at"},
+		{key: "updated_at", column: "timelogs.updated_at"},
+		{key: "time_spent", column: "timelogs.time_spent"},
+		{key: "id", column: "timelogs.id"},
+		{key: "spent_at", column: "COALESCE(timelogs.spent_at, timelogs.created_at)"},
+		{key: "user", column: "(SELECT users.username FROM users WHERE users.id = timelogs.user_id)"},
+		{key: "title", column: ""},
+		{key: "", column: ""},
+	} {
+		if column := orderColumn("timelogs", test.key); column != test.column {
+			t.Errorf("orderColumn(%q) = %q, want %q", test.key, column, test.column)
+		}
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	for _, test := range []struct {
+		options map[string][]string
+		invalid bool
+	}{
+		{options: map[string][]string{}},
+		{options: map[string][]string{"order_by": {"spent_at"}, "sort": {"asc"}}},
+		{options: map[string][]string{"order_by": {"user,created_at"}, "sort": {"asc,desc"}}},
+		{options: map[string][]string{"order_by": {"user"}, "pagination": {"keyset"}}},
+		{options: map[string][]string{"order_by": {"title"}}, invalid: true},
+		{options: map[string][]string{"order_by": {"user,"}}, invalid: true},
+		{options: map[string][]string{"sort": {"up"}}, invalid: true},
+		{options: map[string][]string{"sort": {"asc,"}}, invalid: true},
+		{options: map[string][]string{"order_by": {"user,id"}, "pagination": {"keyset"}}, invalid: true},
+	} {
+		err := validateSort(test.options)
+		if test.invalid && err == nil {
+			t.Errorf("validateSort(%v) = nil, want an error", test.options)
+		}
+		if !test.invalid && err != nil {
+			t.Errorf("validateSort(%v) = %v, want nil", test.options, err)
+		}
+	}
+}
+
+func TestSortOptions(t *testing.T) {
+	for _, test := range []struct {
+		options map[string][]string
+		orderBy []string
+		isAsc   []bool
+	}{
+		{options: map[string][]string{}, orderBy: []string{"created_at"}, isAsc: []bool{false}},
+		{options: map[string][]string{"date_field": {"spent_at"}}, orderBy: []string{"spent_at"}, isAsc: []bool{false}},
+		{options: map[string][]string{"sort": {"asc"}}, orderBy: []string{"created_at"}, isAsc: []bool{true}},
+		{options: map[string][]string{"order_by": {"user,id"}, "sort": {"asc"}}, orderBy: []string{"user", "id"}, isAsc: []bool{true, true}},
+		{options: map[string][]string{"order_by": {"user,id"}, "sort": {"asc,desc"}}, orderBy: []string{"user", "id"}, isAsc: []bool{true, false}},
+	} {
+		orderBy, isAsc := sortOptions(test.options)
+		if !reflect.DeepEqual(orderBy, test.orderBy) || !reflect.DeepEqual(isAsc, test.isAsc) {
+			t.Errorf("sortOptions(%v) = %v, %v, want %v, %v", test.options, orderBy, isAsc, test.orderBy, test.isAsc)
+		}
+	}
+}
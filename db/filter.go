@@ -0,0 +1,137 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+var issuableStates = map[string]bool{"opened": true, "closed": true, "merged": true, "locked": true}
+
+func validateFilters(options map[string][]string) error {
+	if err := validateTimeframe(options); err != nil {
+		return err
+	}
+	if len(options["target_type"]) > 0 && options["target_type"][0] != "issue" && options["target_type"][0] != "merge_request" {
+		return apierror.BadRequest("target_type does not have a valid value")
+	}
+	if len(options["date_field"]) > 0 && options["date_field"][0] != "created_at" && options["date_field"][0] != "spent_at" {
+		return apierror.BadRequest("date_field does not have a valid value")
+	}
+	if len(options["state"]) > 0 && !issuableStates[options["state"][0]] {
+		return apierror.BadRequest("state does not have a valid value")
+	}
+	if len(options["confidential"]) > 0 {
+		if _, err := strconv.ParseBool(options["confidential"][0]); err != nil {
+			return apierror.BadRequest("confidential is invalid")
+		}
+	}
+	if _, err := userIDs(options); err != nil {
+		return apierror.BadRequest("user_ids is invalid")
+	}
+	for _, key := range []string{"min_time_spent", "max_time_spent"} {
+		if len(options[key]) > 0 {
+			if _, err := strconv.Atoi(options[key][0]); err != nil {
+				return apierror.BadRequest(key + " is invalid")
+			}
+		}
+	}
+	return nil
+}
+
+func userIDs(options map[string][]string) ([]int, error) {
+	ids := []int{}
+	if len(options["user_ids"]) == 0 {
+		return ids, nil
+	}
+	for _, id := range strings.Split(options["user_ids"][0], ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(id))
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, n)
+	}
+	return ids, nil
+}
+
+func issuableCondition(issueQuery string, mergeRequestQuery string, value ...interface{}) dbr.Builder {
+	return dbr.Or(
+		dbr.Expr("timelogs.issue_id IN ("+issueQuery+")", value...),
+		dbr.Expr("timelogs.merge_request_id IN ("+mergeRequestQuery+")", value...))
+}
+
+func filtersBuilder(options map[string][]string) []dbr.Builder {
+	conditions := []dbr.Builder{}
+	if len(options["target_type"]) > 0 {
+		switch options["target_type"][0] {
+		case "issue":
+			conditions = append(conditions, dbr.Expr("timelogs.issue_id IS NOT NULL"))
+		case "merge_request":
+			conditions = append(conditions, dbr.Expr("timelogs.merge_request_id IS NOT NULL"))
+		}
+	}
+	if len(options["labels"]) > 0 && options["labels"][0] != "" {
+		for _, label := range strings.Split(options["labels"][0], ",") {
+			conditions = append(conditions, issuableCondition(
+				"SELECT label_links.target_id FROM label_links JOIN labels ON labels.id = label_links.label_id WHERE label_links.target_type = 'Issue' AND labels.title = ?",
+				"SELECT label_links.target_id FROM label_links JOIN labels ON labels.id = label_links.label_id WHERE label_links.target_type = 'MergeRequest' AND labels.title = ?",
+				strings.TrimSpace(label)))
+		}
+	}
+	if len(options["milestone"]) > 0 {
+		if options["milestone"][0] == "None" {
+			conditions = append(conditions, issuableCondition(
+				"SELECT issues.id FROM issues WHERE issues.milestone_id IS NULL",
+				"SELECT merge_requests.id FROM merge_requests WHERE merge_requests.milestone_id IS NULL"))
+		} else {
+			conditions = append(conditions, issuableCondition(
+				"SELECT issues.id FROM issues JOIN milestones ON milestones.id = issues.milestone_id WHERE milestones.title = ?",
+				"SELECT merge_requests.id FROM merge_requests JOIN milestones ON milestones.id = merge_requests.milestone_id WHERE milestones.title = ?",
+				options["milestone"][0]))
+		}
+	}
+	if len(options["state"]) > 0 {
+		conditions = append(conditions, issuableCondition(
+			"SELECT issues.id FROM issues WHERE "+issuableState("issues")+" = ?",
+			"SELECT merge_requests.id FROM merge_requests WHERE "+issuableState("merge_requests")+" = ?",
+			options["state"][0]))
+	}
+	if len(options["confidential"]) > 0 {
+		confidential, _ := strconv.ParseBool(options["confidential"][0])
+		confidentialQuery := dbr.Expr("timelogs.issue_id IN (SELECT issues.id FROM issues WHERE issues.confidential = ?)", confidential)
+		if confidential {
+			conditions = append(conditions, confidentialQuery)
+		} else {
+			conditions = append(conditions, dbr.Or(confidentialQuery, dbr.Expr("timelogs.merge_request_id IS NOT NULL")))
+		}
+	}
+	if ids, _ := userIDs(options); len(ids) > 0 {
+		conditions = append(conditions, dbr.Eq("timelogs.user_id", ids))
+	}
+	if len(options["min_time_spent"]) > 0 {
+		min, _ := strconv.Atoi(options["min_time_spent"][0])
+		conditions = append(conditions, dbr.Gte("timelogs.time_spent", min))
+	}
+	if len(options["max_time_spent"]) > 0 {
+		max, _ := strconv.Atoi(options["max_time_spent"][0])
+		conditions = append(conditions, dbr.Lte("timelogs.time_spent", max))
+	}
+	return conditions
+}
+
+func filters(w dbr.Builder, options map[string][]string) dbr.Builder {
+	conditions := filtersBuilder(options)
+	if w != nil {
+		conditions = append([]dbr.Builder{w}, conditions...)
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return dbr.And(conditions...)
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"strconv"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+func (db *DbAPI) ResolveGroup(idOrPath string) (string, error) {
+	var gID int
+	var w dbr.Builder
+	if _, err := strconv.Atoi(idOrPath); err == nil {
+		w = dbr.Eq("namespaces.id", idOrPath)
+	} else {
+		w = dbr.Expr("namespaces.id = (SELECT routes.source_id FROM routes WHERE routes.source_type = 'Namespace' AND lower(routes.path) = lower(?))", idOrPath)
+	}
+	n, err := db.reader().Select("namespaces.id").
+		From("namespaces").
+		Where(db.restrictGroup(dbr.And(w, dbr.Eq("namespaces.type", "Group")), "namespaces.id")).
+		Load(&gID)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", apierror.NotFound("Group")
+	}
+	return strconv.Itoa(gID), nil
+}
+
+func groupProjects(column string, gID string) dbr.Builder {
+	return dbr.Expr(column+" IN (SELECT projects.id FROM projects WHERE projects.namespace_id IN ("+
+		"WITH RECURSIVE group_tree(id) AS ("+
+		"SELECT namespaces.id FROM namespaces WHERE namespaces.id = ? "+
+		"UNION SELECT namespaces.id FROM namespaces JOIN group_tree ON namespaces.parent_id = group_tree.id"+
+		") SELECT id FROM group_tree))", gID)
+}
+
+func (db *DbAPI) GetTimelogsByGroup(gID string, options map[string][]string) (Timelogs, map[string]string, error) {
+	timelogs := Timelogs{}
+	q, pager, err := paginate(
+		sort(
+			db.reader().Select(timelogColumns()+timelogProject+" as project_id").
+				From("timelogs").
+				LeftJoin("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id").
+				Where(
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								groupProjects(timelogProject, gID),
+								"timelogs",
+								options),
+							options),
+						timelogProject)),
+			options),
+		options)
+	if err != nil {
+		return timelogs, pager, err
+	}
+	return loadTimelogs(q, pager, options)
+}
+
+func (db *DbAPI) GetTimelogsByGroupAndUser(gID string, uID string, options map[string][]string) (Timelogs, map[string]string, error) {
+	timelogs := Timelogs{}
+	q, pager, err := paginate(
+		sort(
+			db.reader().Select(timelogColumns()+timelogProject+" as project_id").
+				From("timelogs").
+				LeftJoin("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id").
+				Where(
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									groupProjects(timelogProject, gID),
+									dbr.Eq("timelogs.user_id", uID)),
+								"timelogs",
+								options),
+							options),
+						timelogProject)),
+			options),
+		options)
+	if err != nil {
+		return timelogs, pager, err
+	}
+	return loadTimelogs(q, pager, options)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"github.com/skilld-labs/dbr"
+)
+
+type Issuable struct {
+	ID             int
+	IID            int
+	ProjectID      int
+	Title          string
+	State          string
+	FullPath       string
+	MilestoneID    int    `db:"!"`
+	MilestoneIID   int    `db:"!"`
+	MilestoneTitle string `db:"!"`
+	Labels         []string
+}
+
+type issuableLabel struct {
+	TargetID int
+	Title    string
+}
+
+func (db *DbAPI) GetIssuesByIDs(ids []int) (map[int]Issuable, error) {
+	return db.getIssuablesByIDs(issueIssuable, "Issue", ids)
+}
+
+func (db *DbAPI) GetMergeRequestsByIDs(ids []int) (map[int]Issuable, error) {
+	return db.getIssuablesByIDs(mergeRequestIssuable, "MergeRequest", ids)
+}
+
+func (db *DbAPI) getIssuablesByIDs(i issuable, labelTargetType string, ids []int) (map[int]Issuable, error) {
+	issuables := map[int]Issuable{}
+	if len(ids) == 0 {
+		return issuables, nil
+	}
+	rows := []Issuable{}
+	_, err := db.reader().Select(i.table+".id, "+i.table+".iid, "+i.table+"."+i.projectColumn+" as project_id, "+i.table+".title, "+issuableState(i.table)+" as state, routes.path as full_path, milestones.id as milestone_id, milestones.iid as milestone_iid, milestones.title as milestone_title").
+		From(i.table).
+		Join("routes", "routes.source_id = "+i.table+"."+i.projectColumn+" AND routes.source_type = 'Project'").
+		LeftJoin("milestones", "milestones.id = "+i.table+".milestone_id").
+		Where(dbr.Eq(i.table+".id", ids)).
+		Load(&rows)
+	if err != nil {
+		return issuables, err
+	}
+	labels := []issuableLabel{}
+	_, err = db.reader().Select("label_links.target_id, labels.title").
+		From("label_links").
+		Join("labels", "labels.id = label_links.label_id").
+		Where(
+			dbr.And(
+				dbr.Eq("label_links.target_type", labelTargetType),
+				dbr.Eq("label_links.target_id", ids))).
+		OrderBy("labels.title").
+		Load(&labels)
+	if err != nil {
+		return issuables, err
+	}
+	for _, row := range rows {
+		row.Labels = []string{}
+		issuables[row.ID] = row
+	}
+	for _, label := range labels {
+		if row, exists := issuables[label.TargetID]; exists {
+			row.Labels = append(row.Labels, label.Title)
+			issuables[label.TargetID] = row
+		}
+	}
+	return issuables, nil
+}
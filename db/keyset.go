@@ -0,0 +1,63 @@
+package db
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+func isKeyset(options map[string][]string) bool {
+	return len(options["pagination"]) > 0 && options["pagination"][0] == "keyset"
+}
+
+func keysetPaginate(q *dbr.SelectBuilder, perPage uint64, options map[string][]string) (*dbr.SelectBuilder, map[string]string, error) {
+	var metadata = make(map[string]string)
+	orderBy, isAsc := sortOptions(options)
+	column := orderColumn("timelogs", orderBy[0])
+	if len(options["id_after"]) > 0 {
+		cursor := strings.SplitN(options["id_after"][0], ".", 2)
+		idAfter, err := strconv.ParseUint(cursor[0], 10, 64)
+		if err != nil {
+			return q, nil, apierror.BadRequest("id_after is invalid")
+		}
+		operator := "<"
+		if isAsc[0] {
+			operator = ">"
+		}
+		if len(cursor) == 2 {
+			sortKey, err := base64.RawURLEncoding.DecodeString(cursor[1])
+			if err != nil {
+				return q, nil, apierror.BadRequest("id_after is invalid")
+			}
+			q = q.Where(dbr.Expr("("+column+", timelogs.id) "+operator+" (?, ?)", string(sortKey), idAfter))
+		} else {
+			q = q.Where(dbr.Expr("("+column+", timelogs.id) "+operator+" (SELECT "+orderColumn("cursor", orderBy[0])+", cursor.id FROM timelogs cursor WHERE cursor.id = ?)", idAfter))
+		}
+	}
+	q.SelectStmt.Column = append(q.SelectStmt.Column, "COALESCE(CAST("+column+" AS text), '') AS sort_key")
+	q = q.Limit(perPage + 1)
+	metadata["Per-Page"] = strconv.FormatUint(perPage, 10)
+	return q, metadata, nil
+}
+
+func loadTimelogs(q *dbr.SelectBuilder, pager map[string]string, options map[string][]string) (Timelogs, map[string]string, error) {
+	timelogs := Timelogs{}
+	_, err := q.Load(&timelogs)
+	if err != nil || !isKeyset(options) {
+		return timelogs, pager, err
+	}
+	perPage, err := strconv.Atoi(pager["Per-Page"])
+	if err != nil {
+		return timelogs, pager, err
+	}
+	if perPage > 0 && len(timelogs) > perPage {
+		timelogs = timelogs[:perPage]
+		last := timelogs[perPage-1]
+		pager["Id-After"] = strconv.Itoa(last.Id) + "." + base64.RawURLEncoding.EncodeToString([]byte(last.SortKey))
+	}
+	return timelogs, pager, nil
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"strconv"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+func (db *DbAPI) ResolveProject(idOrPath string) (string, error) {
+	var pID int
+	var w dbr.Builder
+	if _, err := strconv.Atoi(idOrPath); err == nil {
+		w = dbr.Eq("projects.id", idOrPath)
+	} else {
+		w = dbr.Expr("projects.id = (SELECT routes.source_id FROM routes WHERE routes.source_type = 'Project' AND lower(routes.path) = lower(?))", idOrPath)
+	}
+	n, err := db.reader().Select("projects.id").
+		From("projects").
+		Where(db.restrict(w, "projects.id")).
+		Load(&pID)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", apierror.NotFound("Project")
+	}
+	return strconv.Itoa(pID), nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/skilld-labs/dbr"
+)
+
+const replicaCheckInterval = 10 * time.Second
+
+type replicaSet struct {
+	sessions []*dbr.Session
+	healthy  []int32
+	next     uint32
+}
+
+func NewReplicas(cfg Config) ([]*dbr.Session, error) {
+	replicas := []*dbr.Session{}
+	for _, replicaDSN := range cfg.ReplicaDSNs {
+		replicaCfg := cfg
+		replicaCfg.DSN = replicaDSN
+		r, err := New(replicaCfg)
+		if err != nil {
+			return replicas, err
+		}
+		replicas = append(replicas, r)
+	}
+	return replicas, nil
+}
+
+func newReplicaSet(sessions []*dbr.Session) *replicaSet {
+	if len(sessions) == 0 {
+		return nil
+	}
+	rs := &replicaSet{sessions: sessions, healthy: make([]int32, len(sessions))}
+	rs.check()
+	go func() {
+		for range time.Tick(replicaCheckInterval) {
+			rs.check()
+		}
+	}()
+	return rs
+}
+
+func (rs *replicaSet) check() {
+	for n, s := range rs.sessions {
+		var healthy int32
+		if err := s.Ping(); err == nil {
+			healthy = 1
+		} else if atomic.LoadInt32(&rs.healthy[n]) == 1 {
+			log.Printf("database replica %d is unhealthy: %s", n, err.Error())
+		}
+		atomic.StoreInt32(&rs.healthy[n], healthy)
+	}
+}
+
+func (rs *replicaSet) pick() *dbr.Session {
+	if rs == nil || len(rs.sessions) == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(&rs.next, 1))
+	for i := 0; i < len(rs.sessions); i++ {
+		n := (start + i) % len(rs.sessions)
+		if atomic.LoadInt32(&rs.healthy[n]) == 1 {
+			return rs.sessions[n]
+		}
+	}
+	return nil
+}
+
+func (db *DbAPI) reader() *dbr.Session {
+	if r := db.replicas.pick(); r != nil {
+		return r
+	}
+	return db.Db
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"log"
+
+	"github.com/skilld-labs/dbr"
+)
+
+var hasSpentAt bool
+var hasUserTimezone bool
+var hasIssueState bool
+var hasMergeRequestState bool
+var hasIterations bool
+
+func detectSchema(d *dbr.Session) {
+	hasSpentAt = hasColumn(d, "timelogs", "spent_at")
+	hasUserTimezone = hasColumn(d, "users", "timezone")
+	hasIssueState = hasColumn(d, "issues", "state")
+	hasMergeRequestState = hasColumn(d, "merge_requests", "state")
+	hasIterations = hasColumn(d, "issues", "sprint_id")
+}
+
+func hasColumn(d *dbr.Session, table string, column string) bool {
+	var count int
+	_, err := d.Select("count(*)").
+		From("information_schema.columns").
+		Where(
+			dbr.And(
+				dbr.Eq("table_name", table),
+				dbr.Eq("column_name", column))).
+		Load(&count)
+	if err != nil {
+		log.Println("unable to detect the " + table + "." + column + " column: " + err.Error())
+	}
+	return count > 0
+}
+
+func spentAt(alias string) string {
+	if hasSpentAt {
+		return "COALESCE(" + alias + ".spent_at, " + alias + ".created_at)"
+	}
+	return alias + ".created_at"
+}
+
+// issuableState is the state name of an issue or merge request, read from
+// state_id on GitLab versions that dropped the state column.
+func issuableState(table string) string {
+	if (table == "issues" && hasIssueState) || (table == "merge_requests" && hasMergeRequestState) {
+		return table + ".state"
+	}
+	return "(CASE " + table + ".state_id WHEN 1 THEN 'opened' WHEN 2 THEN 'closed' WHEN 3 THEN 'merged' WHEN 4 THEN 'locked' END)"
+}
+
+func dateField(options map[string][]string) string {
+	if len(options["date_field"]) > 0 && options["date_field"][0] == "spent_at" {
+		return "spent_at"
+	}
+	return "created_at"
+}
+
+func dateColumn(table string, options map[string][]string) string {
+	if table == "timelogs" && dateField(options) == "spent_at" {
+		return spentAt(table)
+	}
+	return table + ".created_at"
+}
+
+func timelogColumns() string {
+	return "timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, " + spentAt("timelogs") + " as spent_at, timelogs.issue_id, COALESCE((SELECT issues.iid FROM issues WHERE issues.id = timelogs.issue_id), 0) as issue_iid, timelogs.merge_request_id, "
+}
+
+func userTimezone() string {
+	if hasUserTimezone {
+		return "COALESCE(timezone, '') as timezone"
+	}
+	return "'' as timezone"
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+type TimelogSummary struct {
+	Key       string
+	TimeSpent int
+	Count     int
+}
+
+type TimelogSummaries []TimelogSummary
+
+var summaryGroups = map[string]string{
+	"user":          "CAST(timelogs.user_id AS text)",
+	"project":       "CAST(" + timelogProject + " AS text)",
+	"issue":         "CAST(timelogs.issue_id AS text)",
+	"merge_request": "CAST(timelogs.merge_request_id AS text)",
+	"day":           "to_char(date_trunc('day', timelogs.created_at), 'YYYY-MM-DD')",
+	"week":          "to_char(date_trunc('week', timelogs.created_at), 'YYYY-MM-DD')",
+	"month":         "to_char(date_trunc('month', timelogs.created_at), 'YYYY-MM')",
+}
+
+func (db *DbAPI) GetTimelogsSummary(groupBy string, options map[string][]string) (TimelogSummaries, error) {
+	summaries := TimelogSummaries{}
+	key, exists := summaryGroups[groupBy]
+	if !exists {
+		return summaries, apierror.BadRequest("group_by does not have a valid value")
+	}
+	key = strings.Replace(key, "timelogs.created_at", db.localDateColumn("timelogs", options), 1)
+	if err := validateFilters(options); err != nil {
+		return summaries, err
+	}
+	var w dbr.Builder
+	if groupBy == "issue" {
+		w = dbr.Expr("timelogs.issue_id IS NOT NULL")
+	}
+	if groupBy == "merge_request" {
+		w = dbr.Expr("timelogs.merge_request_id IS NOT NULL")
+	}
+	query := db.reader().Select(key+" AS key", "SUM(timelogs.time_spent) AS time_spent", "COUNT(*) AS count").
+		From("timelogs").
+		LeftJoin("issues", "issues.id = timelogs.issue_id").
+		LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id")
+	if w = db.restrictTimelogs(filters(db.timeframe(w, "timelogs", options), options), timelogProject); w != nil {
+		query = query.Where(w)
+	}
+	_, err := query.GroupBy(key).OrderBy(key).Load(&summaries)
+	return summaries, err
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+func parseTimeBound(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, errors.New("invalid date " + value)
+}
+
+// loadLocation loads an IANA time zone; "Local" is the server time zone,
+// unknown to PostgreSQL, and is refused.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, errors.New("unknown time zone " + name)
+	}
+	return time.LoadLocation(name)
+}
+
+func validateTimeframe(options map[string][]string) error {
+	if len(options["tz"]) > 0 {
+		if _, err := loadLocation(options["tz"][0]); err != nil {
+			return apierror.BadRequest("tz does not have a valid value")
+		}
+	}
+	for _, key := range []string{"since", "until"} {
+		if len(options[key]) > 0 {
+			if _, _, err := parseTimeBound(options[key][0], time.UTC); err != nil {
+				return apierror.BadRequest(key + " is invalid, expected an ISO 8601 date or datetime")
+			}
+		}
+	}
+	return nil
+}
+
+func (db *DbAPI) Location(options map[string][]string) *time.Location {
+	if len(options["tz"]) > 0 {
+		if loc, err := loadLocation(options["tz"][0]); err == nil {
+			return loc
+		}
+	}
+	if db.viewerTimezone != "" {
+		if loc, err := loadLocation(db.viewerTimezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+func (db *DbAPI) timeframeBuilder(table string, options map[string][]string) dbr.Builder {
+	var timeframeQuery dbr.Builder = nil
+	var sinceQuery dbr.Builder = nil
+	var untilQuery dbr.Builder = nil
+	loc := db.Location(options)
+	column := dateColumn(table, options)
+
+	if len(options["since"]) > 0 {
+		if since, _, err := parseTimeBound(options["since"][0], loc); err == nil {
+			sinceQuery = dbr.Gte(column, since.UTC())
+			timeframeQuery = sinceQuery
+		}
+	}
+
+	if len(options["until"]) > 0 {
+		if until, dateOnly, err := parseTimeBound(options["until"][0], loc); err == nil {
+			if dateOnly {
+				untilQuery = dbr.Lt(column, until.AddDate(0, 0, 1).UTC())
+			} else {
+				untilQuery = dbr.Lte(column, until.UTC())
+			}
+			timeframeQuery = untilQuery
+		}
+	}
+
+	if sinceQuery != nil && untilQuery != nil {
+		timeframeQuery = dbr.And(sinceQuery, untilQuery)
+	}
+
+	return timeframeQuery
+}
+
+func (db *DbAPI) timeframe(w dbr.Builder, table string, options map[string][]string) dbr.Builder {
+	tf := db.timeframeBuilder(table, options)
+	if w == nil {
+		return tf
+	}
+	if tf != nil {
+		w = dbr.And(w, tf)
+	}
+	return w
+}
+
+func (db *DbAPI) localDateColumn(table string, options map[string][]string) string {
+	return "(" + dateColumn(table, options) + " AT TIME ZONE 'UTC' AT TIME ZONE '" + strings.Replace(db.Location(options).String(), "'", "''", -1) + "')"
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	for _, test := range []struct {
+		value    string
+		time     time.Time
+		dateOnly bool
+		invalid  bool
+	}{
+		{value: "2020-01-31", time: time.Date(2020, 1, 31, 0, 0, 0, 0, paris), dateOnly: true},
+		{value: "2020-01-31T10:30:00Z", time: time.Date(2020, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{value: "2020-01-31T10:30:00+02:00", time: time.Date(2020, 1, 31, 8, 30, 0, 0, time.UTC)},
+		{value: "2020-01-31T10:30:00.5Z", time: time.Date(2020, 1, 31, 10, 30, 0, 500000000, time.UTC)},
+		{value: "2020-01-31T10:30:00", time: time.Date(2020, 1, 31, 10, 30, 0, 0, paris)},
+		{value: "2020-01-31T10:30", time: time.Date(2020, 1, 31, 10, 30, 0, 0, paris)},
+		{value: "2020-01-31 10:30:00", time: time.Date(2020, 1, 31, 10, 30, 0, 0, paris)},
+		{value: "2020-01-31 10:30", time: time.Date(2020, 1, 31, 10, 30, 0, 0, paris)},
+		{value: "", invalid: true},
+		{value: "2020-02-30", invalid: true},
+		{value: "31/01/2020", invalid: true},
+		{value: "yesterday", invalid: true},
+	} {
+		bound, dateOnly, err := parseTimeBound(test.value, paris)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("parseTimeBound(%q) = %v, want an error", test.value, bound)
+			}
+			continue
+		}
+		if err != nil || !bound.Equal(test.time) || dateOnly != test.dateOnly {
+			t.Errorf("parseTimeBound(%q) = %v, %v, %v, want %v, %v", test.value, bound, dateOnly, err, test.time, test.dateOnly)
+		}
+	}
+}
+
+func TestValidateTimeframe(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Paris"); err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	for _, test := range []struct {
+		options map[string][]string
+		invalid bool
+	}{
+		{options: map[string][]string{}},
+		{options: map[string][]string{"since": {"2020-01-01"}, "until": {"2020-01-31T23:59:59Z"}}},
+		{options: map[string][]string{"tz": {"Europe/Paris"}}},
+		{options: map[string][]string{"tz": {"UTC"}}},
+		{options: map[string][]string{"tz": {"Local"}}, invalid: true},
+		{options: map[string][]string{"tz": {"Mars/Olympus"}}, invalid: true},
+		{options: map[string][]string{"since": {"last week"}}, invalid: true},
+		{options: map[string][]string{"until": {"2020-13-01"}}, invalid: true},
+	} {
+		err := validateTimeframe(test.options)
+		if test.invalid && err == nil {
+			t.Errorf("validateTimeframe(%v) = nil, want an error", test.options)
+		}
+		if !test.invalid && err != nil {
+			t.Errorf("validateTimeframe(%v) = %v, want nil", test.options, err)
+		}
+	}
+}
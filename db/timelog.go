@@ -1,9 +1,12 @@
 package db
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/skilld-labs/dbr"
+
+	"../apierror"
 )
 
 type Timelog struct {
@@ -12,183 +15,375 @@ type Timelog struct {
 	UserID         int
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	SpentAt        time.Time
 	IssueID        int `db:"!"`
+	IssueIID       int
 	MergeRequestID int `db:"!"`
 	ProjectID      int
+	SortKey        string
 }
 
 type Timelogs []Timelog
 
-func (db *DbAPI) GetTimelogs(options map[string][]string) (Timelogs, map[string]string, error) {
-	issueTimelogs := Timelogs{}
-	mergeRequestTimelogs := Timelogs{}
-	tf := timeframe(nil, "timelogs", options)
-
-	query := db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, issues.project_id").
-		From("timelogs").
-		Join("issues", "issues.id = timelogs.issue_id")
-	if tf != nil {
-		query = query.Where(tf)
-	}
-	q, pager, err := paginate(sort(query, options), options)
-	if err != nil {
-		return nil, pager, err
-	}
-	_, err = q.Load(&issueTimelogs)
-	if err != nil {
-		return nil, pager, err
-	}
+const timelogProject = "COALESCE(issues.project_id, merge_requests.target_project_id)"
 
-	query = db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, merge_requests.source_project_id as project_id").
-		From("timelogs").
-		Join("merge_requests", "merge_requests.id = timelogs.merge_request_id")
-	if tf != nil {
-		query = query.Where(tf)
-	}
-	q, pager, err = paginate(sort(query, options), options)
-	if err != nil {
-		return nil, pager, err
-	}
-	_, err = q.Load(&mergeRequestTimelogs)
+func (db *DbAPI) GetTimelogs(options map[string][]string) (Timelogs, map[string]string, error) {
+	timelogs := Timelogs{}
+	q, pager, err := paginate(
+		sort(
+			db.reader().Select(timelogColumns()+timelogProject+" as project_id").
+				From("timelogs").
+				LeftJoin("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id").
+				Where(
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.Expr(timelogProject+" IS NOT NULL"),
+								"timelogs",
+								options),
+							options),
+						timelogProject)),
+			options),
+		options)
 	if err != nil {
-		return nil, pager, err
+		return timelogs, pager, err
 	}
-
-	return append(issueTimelogs, mergeRequestTimelogs...), pager, nil
+	return loadTimelogs(q, pager, options)
 }
 
 func (db *DbAPI) GetTimelogsByIssue(pID string, iIID string, options map[string][]string) (Timelogs, map[string]string, error) {
 	timelogs := Timelogs{}
 	q, pager, err := paginate(
 		sort(
-			db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, issues.project_id").
+			db.reader().Select(timelogColumns()+"issues.project_id").
 				From("timelogs").
 				Join("issues", "issues.id = timelogs.issue_id").
 				Where(
-					timeframe(
-						dbr.And(
-							dbr.Eq("issues.project_id", pID),
-							dbr.Eq("issues.iid", iIID)),
-						"timelogs",
-						options)),
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									dbr.Eq("issues.project_id", pID),
+									dbr.Eq("issues.iid", iIID)),
+								"timelogs",
+								options),
+							options),
+						"issues.project_id")),
 			options),
 		options)
 	if err != nil {
 		return timelogs, pager, err
 	}
-	_, err = q.Load(&timelogs)
-	return timelogs, pager, err
+	return loadTimelogs(q, pager, options)
 }
 
 func (db *DbAPI) GetTimelogsByMergeRequest(pID string, mIID string, options map[string][]string) (Timelogs, map[string]string, error) {
 	timelogs := Timelogs{}
 	q, pager, err := paginate(
 		sort(
-			db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, merge_requests.source_project_id as project_id").
+			db.reader().Select(timelogColumns()+"merge_requests.target_project_id as project_id").
 				From("timelogs").
 				Join("merge_requests", "merge_requests.id = timelogs.merge_request_id").
 				Where(
-					timeframe(
-						dbr.And(
-							dbr.Eq("merge_requests.source_project_id", pID),
-							dbr.Eq("merge_requests.iid", mIID)),
-						"timelogs",
-						options)),
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									dbr.Eq("merge_requests.target_project_id", pID),
+									dbr.Eq("merge_requests.iid", mIID)),
+								"timelogs",
+								options),
+							options),
+						"merge_requests.target_project_id")),
 			options),
 		options)
 	if err != nil {
 		return timelogs, pager, err
 	}
-	_, err = q.Load(&timelogs)
-	return timelogs, pager, err
+	return loadTimelogs(q, pager, options)
 }
 
 func (db *DbAPI) GetTimelogsByUser(uID string, options map[string][]string) (Timelogs, map[string]string, error) {
 	timelogs := Timelogs{}
 	q, pager, err := paginate(
 		sort(
-			db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, issues.project_id").
+			db.reader().Select(timelogColumns()+timelogProject+" as project_id").
 				From("timelogs").
-				Join("users", "users.id = timelogs.user_id").
-				Join("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id").
 				Where(
-					timeframe(
-						dbr.Eq("users.id", uID),
-						"timelogs",
-						options)),
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									dbr.Expr(timelogProject+" IS NOT NULL"),
+									dbr.Eq("timelogs.user_id", uID)),
+								"timelogs",
+								options),
+							options),
+						timelogProject)),
 			options),
 		options)
 	if err != nil {
 		return timelogs, pager, err
 	}
-	_, err = q.Load(&timelogs)
-	return timelogs, pager, err
+	return loadTimelogs(q, pager, options)
 }
 
 func (db *DbAPI) GetTimelogsByProject(pID string, options map[string][]string) (Timelogs, map[string]string, error) {
 	timelogs := Timelogs{}
 	q, pager, err := paginate(
 		sort(
-			db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, issues.project_id").
+			db.reader().Select(timelogColumns()+timelogProject+" as project_id").
 				From("timelogs").
-				Join("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id").
 				Where(
-					timeframe(
-						dbr.Eq("issues.project_id", pID),
-						"timelogs",
-						options)),
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.Expr(timelogProject+" = ?", pID),
+								"timelogs",
+								options),
+							options),
+						timelogProject)),
 			options),
 		options)
 	if err != nil {
 		return timelogs, pager, err
 	}
-	_, err = q.Load(&timelogs)
-	return timelogs, pager, err
+	return loadTimelogs(q, pager, options)
 }
 
 func (db *DbAPI) GetTimelogsByProjectAndUser(pID string, uID string, options map[string][]string) (Timelogs, map[string]string, error) {
 	timelogs := Timelogs{}
 	q, pager, err := paginate(
 		sort(
-			db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, issues.project_id").
+			db.reader().Select(timelogColumns()+timelogProject+" as project_id").
 				From("timelogs").
-				Join("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("issues", "issues.id = timelogs.issue_id").
+				LeftJoin("merge_requests", "merge_requests.id = timelogs.merge_request_id").
 				Where(
-					timeframe(
-						dbr.And(
-							dbr.Eq("issues.project_id", pID),
-							dbr.Eq("timelogs.user_id", uID)),
-						"timelogs",
-						options)),
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									dbr.Expr(timelogProject+" = ?", pID),
+									dbr.Eq("timelogs.user_id", uID)),
+								"timelogs",
+								options),
+							options),
+						timelogProject)),
 			options),
 		options)
 	if err != nil {
 		return timelogs, pager, err
 	}
-	_, err = q.Load(&timelogs)
-	return timelogs, pager, err
+	return loadTimelogs(q, pager, options)
 }
 
 func (db *DbAPI) GetTimelogsByProjectAndIssueAndUser(pID string, iIID string, uID string, options map[string][]string) (Timelogs, map[string]string, error) {
 	timelogs := Timelogs{}
 	q, pager, err := paginate(
 		sort(
-			db.Db.Select("timelogs.id, timelogs.time_spent, timelogs.user_id, timelogs.created_at, timelogs.updated_at, timelogs.issue_id, timelogs.merge_request_id, issues.project_id").
+			db.reader().Select(timelogColumns()+"issues.project_id").
 				From("timelogs").
 				Join("issues", "issues.id = timelogs.issue_id").
 				Where(
-					timeframe(
-						dbr.And(
-							dbr.Eq("issues.project_id", pID),
-							dbr.Eq("issues.iid", iIID),
-							dbr.Eq("timelogs.user_id", uID)),
-						"timelogs",
-						options)),
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									dbr.Eq("issues.project_id", pID),
+									dbr.Eq("issues.iid", iIID),
+									dbr.Eq("timelogs.user_id", uID)),
+								"timelogs",
+								options),
+							options),
+						"issues.project_id")),
+			options),
+		options)
+	if err != nil {
+		return timelogs, pager, err
+	}
+	return loadTimelogs(q, pager, options)
+}
+
+func (db *DbAPI) GetTimelogsByProjectAndMergeRequestAndUser(pID string, mIID string, uID string, options map[string][]string) (Timelogs, map[string]string, error) {
+	timelogs := Timelogs{}
+	q, pager, err := paginate(
+		sort(
+			db.reader().Select(timelogColumns()+"merge_requests.target_project_id as project_id").
+				From("timelogs").
+				Join("merge_requests", "merge_requests.id = timelogs.merge_request_id").
+				Where(
+					db.restrictTimelogs(
+						filters(
+							db.timeframe(
+								dbr.And(
+									dbr.Eq("merge_requests.target_project_id", pID),
+									dbr.Eq("merge_requests.iid", mIID),
+									dbr.Eq("timelogs.user_id", uID)),
+								"timelogs",
+								options),
+							options),
+						"merge_requests.target_project_id")),
 			options),
 		options)
 	if err != nil {
 		return timelogs, pager, err
 	}
-	_, err = q.Load(&timelogs)
-	return timelogs, pager, err
+	return loadTimelogs(q, pager, options)
+}
+
+type issuable struct {
+	table         string
+	projectColumn string
+	timelogColumn string
+	targetType    string
+	featureColumn string
+	notFound      string
+}
+
+var issueIssuable = issuable{table: "issues", projectColumn: "project_id", timelogColumn: "issue_id", targetType: "issue", featureColumn: "issues_access_level", notFound: "Issue"}
+var mergeRequestIssuable = issuable{table: "merge_requests", projectColumn: "target_project_id", timelogColumn: "merge_request_id", targetType: "merge_request", featureColumn: "merge_requests_access_level", notFound: "Merge Request"}
+
+func (db *DbAPI) CreateTimelogOnIssue(pID string, iIID string, uID int, timeSpent int) (Timelog, error) {
+	return db.createTimelog(issueIssuable, pID, iIID, uID, timeSpent)
+}
+
+func (db *DbAPI) CreateTimelogOnMergeRequest(pID string, mIID string, uID int, timeSpent int) (Timelog, error) {
+	return db.createTimelog(mergeRequestIssuable, pID, mIID, uID, timeSpent)
+}
+
+func (db *DbAPI) UpdateTimelogOnIssue(pID string, iIID string, tID string, uID int, timeSpent int) (Timelog, error) {
+	return db.updateTimelog(issueIssuable, pID, iIID, tID, uID, timeSpent, false)
+}
+
+func (db *DbAPI) UpdateTimelogOnMergeRequest(pID string, mIID string, tID string, uID int, timeSpent int) (Timelog, error) {
+	return db.updateTimelog(mergeRequestIssuable, pID, mIID, tID, uID, timeSpent, false)
+}
+
+func (db *DbAPI) DeleteTimelogOnIssue(pID string, iIID string, tID string, uID int) (Timelog, error) {
+	return db.updateTimelog(issueIssuable, pID, iIID, tID, uID, 0, true)
+}
+
+func (db *DbAPI) DeleteTimelogOnMergeRequest(pID string, mIID string, tID string, uID int) (Timelog, error) {
+	return db.updateTimelog(mergeRequestIssuable, pID, mIID, tID, uID, 0, true)
+}
+
+func (db *DbAPI) createTimelog(i issuable, pID string, iid string, uID int, timeSpent int) (Timelog, error) {
+	timelog := Timelog{}
+	tx, err := db.Db.Begin()
+	if err != nil {
+		return timelog, err
+	}
+	defer tx.RollbackUnlessCommitted()
+	if err = db.requireReporter(tx, pID); err != nil {
+		return timelog, err
+	}
+	issuableID, total, err := lockIssuable(tx, i, pID, iid)
+	if err != nil {
+		return timelog, err
+	}
+	if total+timeSpent < 0 {
+		return timelog, apierror.BadRequest("Time to subtract exceeds the total time spent")
+	}
+	now := time.Now()
+	timelog = Timelog{TimeSpent: timeSpent, UserID: uID, CreatedAt: now, UpdatedAt: now, SpentAt: now}
+	if i == issueIssuable {
+		timelog.IssueID = issuableID
+		timelog.IssueIID, err = strconv.Atoi(iid)
+		if err != nil {
+			return timelog, err
+		}
+	} else {
+		timelog.MergeRequestID = issuableID
+	}
+	timelog.ProjectID, err = strconv.Atoi(pID)
+	if err != nil {
+		return timelog, err
+	}
+	columns := "time_spent, user_id, created_at, updated_at, " + i.timelogColumn
+	placeholders := "?, ?, ?, ?, ?"
+	values := []interface{}{timeSpent, uID, now, now, issuableID}
+	if hasSpentAt {
+		columns += ", spent_at"
+		placeholders += ", ?"
+		values = append(values, now)
+	}
+	_, err = tx.SelectBySql("INSERT INTO timelogs ("+columns+") VALUES ("+placeholders+") RETURNING id", values...).Load(&timelog.Id)
+	if err != nil {
+		return timelog, err
+	}
+	return timelog, tx.Commit()
+}
+
+func (db *DbAPI) updateTimelog(i issuable, pID string, iid string, tID string, uID int, timeSpent int, remove bool) (Timelog, error) {
+	timelog := Timelog{}
+	tx, err := db.Db.Begin()
+	if err != nil {
+		return timelog, err
+	}
+	defer tx.RollbackUnlessCommitted()
+	if err = db.requireReporter(tx, pID); err != nil {
+		return timelog, err
+	}
+	issuableID, total, err := lockIssuable(tx, i, pID, iid)
+	if err != nil {
+		return timelog, err
+	}
+	n, err := tx.Select(timelogColumns()+i.table+"."+i.projectColumn+" as project_id").
+		From("timelogs").
+		Join(i.table, i.table+".id = timelogs."+i.timelogColumn).
+		Where(
+			dbr.And(
+				dbr.Eq("timelogs.id", tID),
+				dbr.Eq("timelogs."+i.timelogColumn, issuableID))).
+		Load(&timelog)
+	if err != nil {
+		return timelog, err
+	}
+	if n == 0 {
+		return timelog, apierror.NotFound("Timelog")
+	}
+	if timelog.UserID != uID {
+		return timelog, apierror.Forbidden()
+	}
+	if total-timelog.TimeSpent+timeSpent < 0 {
+		return timelog, apierror.BadRequest("Time to subtract exceeds the total time spent")
+	}
+	if remove {
+		_, err = tx.DeleteFrom("timelogs").Where(dbr.Eq("id", timelog.Id)).Exec()
+	} else {
+		timelog.TimeSpent = timeSpent
+		timelog.UpdatedAt = time.Now()
+		_, err = tx.Update("timelogs").
+			Set("time_spent", timelog.TimeSpent).
+			Set("updated_at", timelog.UpdatedAt).
+			Where(dbr.Eq("id", timelog.Id)).
+			Exec()
+	}
+	if err != nil {
+		return timelog, err
+	}
+	return timelog, tx.Commit()
+}
+
+// lockIssuable locks the issuable pID/iid and returns its id and total time
+// spent. Merge requests are looked up in their target project, the project
+// requireReporter authorized, never in the fork they come from.
+func lockIssuable(tx *dbr.Tx, i issuable, pID string, iid string) (int, int, error) {
+	var issuableID int
+	var total int
+	n, err := tx.SelectBySql("SELECT id FROM "+i.table+" WHERE "+i.projectColumn+" = ? AND iid = ? FOR UPDATE", pID, iid).Load(&issuableID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n == 0 {
+		return 0, 0, apierror.NotFound(i.notFound)
+	}
+	_, err = tx.Select("COALESCE(SUM(time_spent), 0)").From("timelogs").Where(dbr.Eq(i.timelogColumn, issuableID)).Load(&total)
+	return issuableID, total, err
 }
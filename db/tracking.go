@@ -0,0 +1,76 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/skilld-labs/dbr"
+)
+
+type TimeTracking struct {
+	ID             int
+	IID            int
+	ProjectID      int
+	TargetType     string
+	Title          string
+	State          string
+	FullPath       string
+	TimeEstimate   int
+	TotalTimeSpent int
+}
+
+type TimeTrackings []TimeTracking
+
+// GetTimeTrackingByProject returns the estimate and total spent time of the
+// project issues and merge requests having either of them.
+func (db *DbAPI) GetTimeTrackingByProject(pID string, options map[string][]string) (TimeTrackings, error) {
+	trackings := TimeTrackings{}
+	if err := validateFilters(options); err != nil {
+		return trackings, err
+	}
+	if len(options["target_type"]) == 0 || options["target_type"][0] == "issue" {
+		issues, err := db.getTimeTracking(issueIssuable, "Issue", pID, options)
+		if err != nil {
+			return trackings, err
+		}
+		trackings = append(trackings, issues...)
+	}
+	if len(options["target_type"]) == 0 || options["target_type"][0] == "merge_request" {
+		mergeRequests, err := db.getTimeTracking(mergeRequestIssuable, "MergeRequest", pID, options)
+		if err != nil {
+			return trackings, err
+		}
+		trackings = append(trackings, mergeRequests...)
+	}
+	return trackings, nil
+}
+
+func (db *DbAPI) getTimeTracking(i issuable, labelTargetType string, pID string, options map[string][]string) (TimeTrackings, error) {
+	trackings := TimeTrackings{}
+	totalTimeSpent := "(SELECT COALESCE(SUM(timelogs.time_spent), 0) FROM timelogs WHERE timelogs." + i.timelogColumn + " = " + i.table + ".id)"
+	conditions := []dbr.Builder{
+		dbr.Eq(i.table+"."+i.projectColumn, pID),
+		dbr.Expr("(COALESCE(" + i.table + ".time_estimate, 0) > 0 OR " + totalTimeSpent + " <> 0)"),
+	}
+	if len(options["labels"]) > 0 && options["labels"][0] != "" {
+		for _, label := range strings.Split(options["labels"][0], ",") {
+			conditions = append(conditions, dbr.Expr(i.table+".id IN (SELECT label_links.target_id FROM label_links JOIN labels ON labels.id = label_links.label_id WHERE label_links.target_type = ? AND labels.title = ?)", labelTargetType, strings.TrimSpace(label)))
+		}
+	}
+	if len(options["milestone"]) > 0 {
+		if options["milestone"][0] == "None" {
+			conditions = append(conditions, dbr.Expr(i.table+".milestone_id IS NULL"))
+		} else {
+			conditions = append(conditions, dbr.Expr(i.table+".milestone_id IN (SELECT milestones.id FROM milestones WHERE milestones.title = ?)", options["milestone"][0]))
+		}
+	}
+	if len(options["state"]) > 0 {
+		conditions = append(conditions, dbr.Expr(issuableState(i.table)+" = ?", options["state"][0]))
+	}
+	_, err := db.reader().Select(i.table+".id, "+i.table+".iid, "+i.table+"."+i.projectColumn+" as project_id, '"+i.targetType+"' as target_type, "+i.table+".title, "+issuableState(i.table)+" as state, routes.path as full_path, COALESCE("+i.table+".time_estimate, 0) as time_estimate, "+totalTimeSpent+" as total_time_spent").
+		From(i.table).
+		Join("routes", "routes.source_id = "+i.table+"."+i.projectColumn+" AND routes.source_type = 'Project'").
+		Where(db.restrictIssuable(dbr.And(conditions...), i)).
+		OrderBy(i.table + ".iid").
+		Load(&trackings)
+	return trackings, err
+}
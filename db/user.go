@@ -1,21 +1,103 @@
 package db
 
 import (
-	"github.com/skilld-labs/dbr"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
 )
 
+const userCacheTTL = time.Minute
+
 type User struct {
 	ID        int
 	Name      string
 	Username  string
 	Email     string
 	State     string
+	Admin     bool
+	External  bool
+	Timezone  string
 	CreatedAt time.Time
 }
 
+type cachedUser struct {
+	user      User
+	expiresAt time.Time
+}
+
+var userCache = struct {
+	sync.RWMutex
+	users map[int]cachedUser
+}{users: map[int]cachedUser{}}
+
 func (db *DbAPI) GetUserByID(id int) (User, error) {
-	user := User{}
-	_, err := db.Db.Select("id", "name", "username", "email", "state", "created_at").From("users").Where(dbr.Eq("id", id)).Load(&user)
-	return user, err
+	users, err := db.GetUsersByIDs([]int{id})
+	return users[id], err
+}
+
+func (db *DbAPI) GetUsersByIDs(ids []int) (map[int]User, error) {
+	users := map[int]User{}
+	missing := []int{}
+	requested := map[int]bool{}
+	now := time.Now()
+	userCache.RLock()
+	for _, id := range ids {
+		if requested[id] {
+			continue
+		}
+		requested[id] = true
+		if cached, exists := userCache.users[id]; exists && now.Before(cached.expiresAt) {
+			users[id] = cached.user
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	userCache.RUnlock()
+	if len(missing) == 0 {
+		return users, nil
+	}
+	loaded, err := loadUsers(db.reader(), missing)
+	if err != nil {
+		return users, err
+	}
+	userCache.Lock()
+	for id, cached := range userCache.users {
+		if now.After(cached.expiresAt) {
+			delete(userCache.users, id)
+		}
+	}
+	for _, user := range loaded {
+		users[user.ID] = user
+		userCache.users[user.ID] = cachedUser{user: user, expiresAt: now.Add(userCacheTTL)}
+	}
+	userCache.Unlock()
+	return users, nil
+}
+
+func loadUsers(s *dbr.Session, ids []int) ([]User, error) {
+	users := []User{}
+	_, err := s.Select("id", "name", "username", "email", "state", "admin", "external", userTimezone(), "created_at").From("users").Where(dbr.Eq("id", ids)).Load(&users)
+	return users, err
+}
+
+func (db *DbAPI) ResolveUser(idOrUsername string) (string, error) {
+	var uID int
+	var w dbr.Builder
+	if _, err := strconv.Atoi(idOrUsername); err == nil {
+		w = dbr.Eq("id", idOrUsername)
+	} else {
+		w = dbr.Expr("lower(username) = lower(?)", idOrUsername)
+	}
+	n, err := db.reader().Select("id").From("users").Where(w).Load(&uID)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", apierror.NotFound("User")
+	}
+	return strconv.Itoa(uID), nil
 }
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"github.com/skilld-labs/dbr"
+
+	"../apierror"
+)
+
+const (
+	visibilityInternal = 10
+	visibilityPublic   = 20
+	accessReporter     = 20
+	featurePrivate     = 10
+	featureEnabled     = 20
+)
+
+func (db *DbAPI) ForUser(uID int) (*DbAPI, error) {
+	// The viewer is read from the primary, not from the user cache or a
+	// replica, so that revoked admin rights apply to the next request.
+	users, err := loadUsers(db.Db, []int{uID})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, apierror.Unauthorized()
+	}
+	user := users[0]
+	return &DbAPI{Db: db.Db, replicas: db.replicas, viewerID: user.ID, viewerAdmin: user.Admin, viewerExternal: user.External, viewerTimezone: user.Timezone}, nil
+}
+
+func (db *DbAPI) restrict(w dbr.Builder, column string) dbr.Builder {
+	if db.viewerAdmin {
+		return w
+	}
+	var r dbr.Builder
+	if db.viewerID == 0 {
+		r = dbr.Expr(column+" IN (SELECT projects.id FROM projects WHERE projects.visibility_level = ?)", visibilityPublic)
+	} else {
+		visibility := "projects.visibility_level IN (?, ?)"
+		values := []interface{}{visibilityInternal, visibilityPublic}
+		if db.viewerExternal {
+			visibility = "projects.visibility_level = ?"
+			values = []interface{}{visibilityPublic}
+		}
+		r = dbr.Expr(column+" IN (SELECT projects.id FROM projects WHERE "+visibility+" "+
+			"UNION SELECT project_authorizations.project_id FROM project_authorizations WHERE project_authorizations.user_id = ? AND project_authorizations.access_level >= ?)",
+			append(values, db.viewerID, accessReporter)...)
+	}
+	if w == nil {
+		return r
+	}
+	return dbr.And(w, r)
+}
+
+// restrictGroup keeps the groups the viewer can read: public groups, internal
+// ones unless they are external, the groups (and their subgroups) they are a
+// member of and the groups of the projects they are authorized on.
+func (db *DbAPI) restrictGroup(w dbr.Builder, column string) dbr.Builder {
+	if db.viewerAdmin {
+		return w
+	}
+	visibility := "namespaces.visibility_level IN (?, ?)"
+	values := []interface{}{visibilityInternal, visibilityPublic}
+	if db.viewerID == 0 || db.viewerExternal {
+		visibility = "namespaces.visibility_level = ?"
+		values = []interface{}{visibilityPublic}
+	}
+	r := dbr.Expr(column+" IN (SELECT namespaces.id FROM namespaces WHERE "+visibility+" "+
+		"UNION SELECT projects.namespace_id FROM projects JOIN project_authorizations ON project_authorizations.project_id = projects.id WHERE project_authorizations.user_id = ? "+
+		"UNION (WITH RECURSIVE member_groups(id) AS ("+
+		"SELECT members.source_id FROM members WHERE members.source_type = 'Namespace' AND members.user_id = ? AND members.requested_at IS NULL "+
+		"UNION SELECT namespaces.id FROM namespaces JOIN member_groups ON namespaces.parent_id = member_groups.id"+
+		") SELECT id FROM member_groups))",
+		append(values, db.viewerID, db.viewerID)...)
+	if w == nil {
+		return r
+	}
+	return dbr.And(w, r)
+}
+
+// issuableVisibility is the condition for the viewer to see the issuable
+// aliased as alias: its feature must be enabled, or private and the viewer a
+// Reporter, and confidential issues are only shown to Reporters, their author
+// and their assignees.
+func (db *DbAPI) issuableVisibility(i issuable, alias string) (string, []interface{}) {
+	member := alias + "." + i.projectColumn + " IN (SELECT project_authorizations.project_id FROM project_authorizations WHERE project_authorizations.user_id = ? AND project_authorizations.access_level >= ?)"
+	level := "COALESCE((SELECT project_features." + i.featureColumn + " FROM project_features WHERE project_features.project_id = " + alias + "." + i.projectColumn + "), ?)"
+	query := "(" + level + " >= ? OR (" + level + " >= ? AND " + member + "))"
+	values := []interface{}{featureEnabled, featureEnabled, featureEnabled, featurePrivate, db.viewerID, accessReporter}
+	if i == issueIssuable {
+		query += " AND (" + alias + ".confidential = false OR " + alias + ".author_id = ? OR " + member +
+			" OR EXISTS (SELECT 1 FROM issue_assignees WHERE issue_assignees.issue_id = " + alias + ".id AND issue_assignees.user_id = ?))"
+		values = append(values, db.viewerID, db.viewerID, accessReporter, db.viewerID)
+	}
+	return query, values
+}
+
+func (db *DbAPI) restrictIssuable(w dbr.Builder, i issuable) dbr.Builder {
+	w = db.restrict(w, i.table+"."+i.projectColumn)
+	if db.viewerAdmin {
+		return w
+	}
+	query, values := db.issuableVisibility(i, i.table)
+	return dbr.And(w, dbr.Expr(query, values...))
+}
+
+func (db *DbAPI) restrictTimelogs(w dbr.Builder, column string) dbr.Builder {
+	w = db.restrict(w, column)
+	if db.viewerAdmin {
+		return w
+	}
+	conditions := []dbr.Builder{w}
+	for _, i := range []issuable{issueIssuable, mergeRequestIssuable} {
+		query, values := db.issuableVisibility(i, "visible")
+		conditions = append(conditions, dbr.Expr("(timelogs."+i.timelogColumn+" IS NULL OR EXISTS (SELECT 1 FROM "+i.table+" visible WHERE visible.id = timelogs."+i.timelogColumn+" AND "+query+"))", values...))
+	}
+	return dbr.And(conditions...)
+}
+
+func (db *DbAPI) requireReporter(tx *dbr.Tx, pID string) error {
+	if db.viewerAdmin {
+		return nil
+	}
+	var count int
+	_, err := tx.Select("count(*)").
+		From("project_authorizations").
+		Where(
+			dbr.And(
+				dbr.Eq("project_authorizations.project_id", pID),
+				dbr.Eq("project_authorizations.user_id", db.viewerID),
+				dbr.Gte("project_authorizations.access_level", accessReporter))).
+		Load(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return apierror.Forbidden()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Writer interface {
+	WriteRow(row []interface{}) error
+	Flush() error
+	Close() error
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteRow(row []interface{}) error {
+	record := []string{}
+	for _, cell := range row {
+		value := fmt.Sprint(cell)
+		if _, isString := cell.(string); isString && value != "" && strings.ContainsAny(value[:1], "=+-@") {
+			value = "'" + value
+		}
+		record = append(record, value)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+type xlsxWriter struct {
+	z     *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+var xlsxParts = [][]string{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func NewXLSX(w io.Writer) (Writer, error) {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := z.Create(part[0])
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, part[1]); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &xlsxWriter{z: z, sheet: sheet}, err
+}
+
+func (x *xlsxWriter) WriteRow(row []interface{}) error {
+	x.row++
+	cells := `<row r="` + strconv.Itoa(x.row) + `">`
+	for _, cell := range row {
+		switch value := cell.(type) {
+		case int:
+			cells += `<c t="n"><v>` + strconv.Itoa(value) + `</v></c>`
+		case int64:
+			cells += `<c t="n"><v>` + strconv.FormatInt(value, 10) + `</v></c>`
+		default:
+			var escaped strings.Builder
+			if err := xml.EscapeText(&escaped, []byte(fmt.Sprint(value))); err != nil {
+				return err
+			}
+			cells += `<c t="inlineStr"><is><t xml:space="preserve">` + escaped.String() + `</t></is></c>`
+		}
+	}
+	_, err := io.WriteString(x.sheet, cells+`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Flush() error {
+	return x.z.Flush()
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.z.Close()
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCSVWriteRow(t *testing.T) {
+	for _, test := range []struct {
+		row  []interface{}
+		line string
+	}{
+		{row: []interface{}{"2020-01-31", "alice", 3600, "1h"}, line: "2020-01-31,alice,3600,1h"},
+		{row: []interface{}{"=SUM(A1:A2)", "+1", "-1", "@cmd"}, line: "'=SUM(A1:A2),'+1,'-1,'@cmd"},
+		{row: []interface{}{-1800, "", "a = b"}, line: "-1800,,a = b"},
+		{row: []interface{}{"Fix \"login\", again"}, line: `"Fix ""login"", again"`},
+	} {
+		var b bytes.Buffer
+		w := NewCSV(&b)
+		if err := w.WriteRow(test.row); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if line := strings.TrimSuffix(b.String(), "\n"); line != test.line {
+			t.Errorf("WriteRow(%v) = %q, want %q", test.row, line, test.line)
+		}
+	}
+}
+
+func TestXLSXWriteRow(t *testing.T) {
+	type cell struct {
+		Type   string `xml:"t,attr"`
+		Value  string `xml:"v"`
+		Inline string `xml:"is>t"`
+	}
+	type row struct {
+		Number string `xml:"r,attr"`
+		Cells  []cell `xml:"c"`
+	}
+	type worksheet struct {
+		Rows []row `xml:"sheetData>row"`
+	}
+	for _, test := range []struct {
+		rows  [][]interface{}
+		cells [][]cell
+	}{
+		{
+			rows:  [][]interface{}{},
+			cells: [][]cell{},
+		},
+		{
+			rows: [][]interface{}{{"Date", "Seconds"}, {"2020-01-31", 3600}, {"=1+1", int64(-60)}},
+			cells: [][]cell{
+				{{Type: "inlineStr", Inline: "Date"}, {Type: "inlineStr", Inline: "Seconds"}},
+				{{Type: "inlineStr", Inline: "2020-01-31"}, {Type: "n", Value: "3600"}},
+				{{Type: "inlineStr", Inline: "=1+1"}, {Type: "n", Value: "-60"}},
+			},
+		},
+		{
+			rows:  [][]interface{}{{"<b> & \"c\"", " padded "}},
+			cells: [][]cell{{{Type: "inlineStr", Inline: "<b> & \"c\""}, {Type: "inlineStr", Inline: " padded "}}},
+		},
+	} {
+		var b bytes.Buffer
+		w, err := NewXLSX(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range test.rows {
+			if err = w.WriteRow(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+		if err != nil {
+			t.Fatalf("%v: invalid zip: %v", test.rows, err)
+		}
+		parts := map[string][]byte{}
+		for _, f := range z.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts[f.Name], err = ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, part := range append(xlsxParts, []string{"xl/worksheets/sheet1.xml"}) {
+			if _, exists := parts[part[0]]; !exists {
+				t.Errorf("%v: part %s is missing", test.rows, part[0])
+			}
+		}
+		var sheet worksheet
+		if err = xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+			t.Fatalf("%v: invalid sheet: %v", test.rows, err)
+		}
+		if len(sheet.Rows) != len(test.cells) {
+			t.Fatalf("%v: %d rows, want %d", test.rows, len(sheet.Rows), len(test.cells))
+		}
+		for n, r := range sheet.Rows {
+			if r.Number != strconv.Itoa(n+1) {
+				t.Errorf("%v: row %d is numbered %s", test.rows, n+1, r.Number)
+			}
+			if len(r.Cells) != len(test.cells[n]) {
+				t.Errorf("%v: row %d has %d cells, want %d", test.rows, n+1, len(r.Cells), len(test.cells[n]))
+				continue
+			}
+			for m, c := range r.Cells {
+				if c != test.cells[n][m] {
+					t.Errorf("%v: cell %d,%d = %+v, want %+v", test.rows, n+1, m+1, c, test.cells[n][m])
+				}
+			}
+		}
+	}
+}
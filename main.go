@@ -5,88 +5,81 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	"./api"
+	"./api/times"
 	"./api/v4"
+	"./config"
 	"./db"
 	"./router"
 )
 
 func main() {
-	bindAddress := flag.String("bindAddress", "", "The address (incl. port) to bind")
-	dbSocketPath := flag.String("dbSocketPath", "", "The database socket path")
-	dbName := flag.String("dbName", "", "The database name")
-	gitlabSocketPath := flag.String("gitlabSocketPath", "", "The gitlab workhorse socket path")
-	uri := flag.String("uri", "", "The gitlab instance uri")
-	tlsCertificate := flag.String("tlsCertificate", "", "The TLS certificate file")
-	tlsKey := flag.String("tlsKey", "", "The TLS key file")
-	flag.Parse()
+	cfg, err := config.Parse(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	handlers := []string{}
+	for _, route := range (&apiv4.ApiAPI{}).Routes() {
+		handlers = append(handlers, route.Name)
+	}
+	if err = cfg.Validate(handlers); err != nil {
+		log.Fatal(err.Error())
+	}
 
-	d, err := db.New(db.Config{SocketPath: *dbSocketPath, Name: *dbName})
+	dbCfg := db.Config{
+		SocketPath:      cfg.DbSocketPath,
+		Host:            cfg.DbHost,
+		Port:            cfg.DbPort,
+		Name:            cfg.DbName,
+		User:            cfg.DbUser,
+		Password:        cfg.DbPassword,
+		SSLMode:         cfg.DbSSLMode,
+		SSLRootCert:     cfg.DbSSLRootCert,
+		SSLCert:         cfg.DbSSLCert,
+		SSLKey:          cfg.DbSSLKey,
+		DSN:             cfg.DbDSN,
+		ReplicaDSNs:     cfg.DbReplicaDSN,
+		MaxOpenConns:    cfg.DbMaxOpenConns,
+		MaxIdleConns:    cfg.DbMaxIdleConns,
+		ConnMaxLifetime: cfg.DbConnMaxLifetime,
+	}
+	d, err := db.New(dbCfg)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
-	dapi := db.NewDbAPI(d)
+	replicas, err := db.NewReplicas(dbCfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	dapi := db.NewDbAPI(d, replicas...)
 
-	a := api.New(api.Config{DbAPI: *dapi})
+	if cfg.LocalesPath != "" {
+		if err = times.LoadLocales(cfg.LocalesPath); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	durations := times.Formatter{HoursPerDay: cfg.HoursPerDay, DaysPerWeek: cfg.DaysPerWeek, LimitToHours: cfg.LimitToHours}
+	a := api.New(api.Config{DbAPI: *dapi, Uri: cfg.Uri, Durations: durations})
 	aapiV4 := apiv4.NewApiAPI(a)
 
-	enableTls := *tlsCertificate != "" || *tlsKey != ""
-	r := router.New(router.Config{GitlabSocket: *gitlabSocketPath, Uri: *uri, TLS: enableTls})
+	enableTls := cfg.TLSCertificate != ""
+	r := router.New(router.Config{GitlabSocket: cfg.GitlabSocketPath, Uri: cfg.Uri, TLS: enableTls})
 	rapi := router.NewRouterAPI(r)
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetTimelogs",
-		Auth:          true,
-	})
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/projects/{projectID}/issues/{issueIID}/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetIssueTimelogs",
-		Auth:          true,
-	})
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/projects/{projectID}/merge_requests/{mergeRequestIID}/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetMergeRequestTimelogs",
-		Auth:          true,
-	})
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/users/{userID}/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetUserTimelogs",
-		Auth:          true,
-	})
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/projects/{projectID}/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetProjectTimelogs",
-		Auth:          true,
-	})
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/projects/{projectID}/users/{userID}/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetUserTimelogsByProject",
-		Auth:          true,
-	})
-	rapi.AddRoute(router.Route{
-		Path:          "/api/v4/projects/{projectID}/issues/{issueIID}/users/{userID}/time_logs",
-		Method:        "GET",
-		HandlerStruct: &aapiV4,
-		HandlerMethod: "GetUserTimelogsByProjectAndIssue",
-		Auth:          true,
-	})
+	for _, route := range aapiV4.Routes() {
+		if !cfg.RouteEnabled(route.Name) {
+			continue
+		}
+		if err = rapi.AddRoute(route); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
 
 	srv := &http.Server{
 		Handler: r,
-		Addr:    *bindAddress,
+		Addr:    cfg.BindAddress,
 	}
 
 	if enableTls {
@@ -103,7 +96,7 @@ func main() {
 			},
 		}
 		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler), 0)
-		log.Fatal(srv.ListenAndServeTLS(*tlsCertificate, *tlsKey))
+		log.Fatal(srv.ListenAndServeTLS(cfg.TLSCertificate, cfg.TLSKey))
 	} else {
 		log.Fatal(srv.ListenAndServe())
 	}
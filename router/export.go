@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"../apierror"
+	"../export"
+)
+
+const exportPageSize = "1000"
+
+var exportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type Exportable interface {
+	ExportHeader() []string
+	ExportRows() [][]interface{}
+}
+
+func exportFormat(req *http.Request) (string, error) {
+	if format := req.Form.Get("format"); format != "" {
+		if _, exists := exportContentTypes[format]; !exists && format != "json" {
+			return "", apierror.BadRequest("format does not have a valid value, allowed values are: json, csv, xlsx")
+		}
+		if format == "json" {
+			return "", nil
+		}
+		return format, nil
+	}
+	accept := req.Header.Get("Accept")
+	for format, contentType := range exportContentTypes {
+		if strings.Contains(accept, strings.Split(contentType, ";")[0]) {
+			return format, nil
+		}
+	}
+	return "", nil
+}
+
+// exportAll streams every row matching the query, walking the pages with keyset
+// pagination so that the whole result set is never held in memory.
+func exportAll(ctx context.Context, w http.ResponseWriter, route Route, vars map[string]string, form url.Values, format string) error {
+	query := url.Values{}
+	for key, values := range form {
+		query[key] = values
+	}
+	query.Del("page")
+	query.Del("id_after")
+	query.Set("pagination", "keyset")
+	query.Set("per_page", exportPageSize)
+	query.Set("with_issuable", "true")
+	resp, metadata, err := route.Handler.Handle(ctx, vars, query)
+	if err != nil {
+		return err
+	}
+	exportable, ok := resp.(Exportable)
+	if !ok {
+		return apierror.BadRequest("format is not supported on this route")
+	}
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", "attachment; filename=\"time_logs."+format+"\"")
+	w.WriteHeader(http.StatusOK)
+	var ew export.Writer
+	if format == "xlsx" {
+		if ew, err = export.NewXLSX(w); err != nil {
+			log.Println(err.Error())
+			return nil
+		}
+	} else {
+		ew = export.NewCSV(w)
+	}
+	header := []interface{}{}
+	for _, column := range exportable.ExportHeader() {
+		header = append(header, column)
+	}
+	if err = ew.WriteRow(header); err != nil {
+		log.Println(err.Error())
+		return nil
+	}
+	for {
+		for _, row := range exportable.ExportRows() {
+			if err = ew.WriteRow(row); err != nil {
+				log.Println(err.Error())
+				return nil
+			}
+		}
+		if err = ew.Flush(); err != nil {
+			log.Println(err.Error())
+			return nil
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		idAfter := metadata["Id-After"]
+		if idAfter == "" {
+			break
+		}
+		query.Set("id_after", idAfter)
+		if resp, metadata, err = route.Handler.Handle(ctx, vars, query); err != nil {
+			log.Println(err.Error())
+			return nil
+		}
+		if exportable, ok = resp.(Exportable); !ok {
+			break
+		}
+	}
+	if err = ew.Close(); err != nil {
+		log.Println(err.Error())
+	}
+	return nil
+}
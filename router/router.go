@@ -1,6 +1,7 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
@@ -8,11 +9,12 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"reflect"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sensiblecodeio/tiny-ssl-reverse-proxy/pkg/wsproxy"
+
+	"../apierror"
 )
 
 var u *url.URL
@@ -27,17 +29,35 @@ type Config struct {
 	TLS          bool
 }
 
+type contextKey string
+
+const (
+	userIDKey         contextKey = "userID"
+	acceptLanguageKey contextKey = "acceptLanguage"
+)
+
+type Handler interface {
+	Handle(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error)
+}
+
+type HandlerFunc func(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, vars map[string]string, query url.Values) (interface{}, map[string]string, error) {
+	return f(ctx, vars, query)
+}
+
 type Route struct {
-	Path                string
-	Method              string
-	Auth                bool
-	HandlerStruct       interface{}
-	HandlerMethod       string
-	HandlerQueryStrings []string
+	Name    string
+	Path    string
+	Method  string
+	Auth    bool
+	Scope   string
+	Handler Handler
 }
 
 type RouterAPI struct {
 	router *mux.Router
+	routes map[string]bool
 }
 
 func New(rcfg Config) *mux.Router {
@@ -49,53 +69,100 @@ func New(rcfg Config) *mux.Router {
 	usp = rcfg.GitlabSocket
 	ust = &http.Transport{Dial: unixSocketDial}
 	tls = rcfg.TLS
-	r := mux.NewRouter().StrictSlash(true)
+	r := mux.NewRouter().StrictSlash(true).UseEncodedPath()
 	r.NotFoundHandler = http.HandlerFunc(proxyGitlab)
 	return r
 }
 
 func NewRouterAPI(r *mux.Router) RouterAPI {
 	nc = &http.Client{Timeout: time.Second * 10, Transport: ust}
-	return RouterAPI{router: r}
+	return RouterAPI{router: r, routes: map[string]bool{}}
+}
+
+func UserID(ctx context.Context) (int, bool) {
+	userID, exists := ctx.Value(userIDKey).(int)
+	return userID, exists
 }
 
-func (r *RouterAPI) AddRoute(route Route) {
-	r.router.Path(route.Path).Methods(route.Method).HandlerFunc(func(route Route) http.HandlerFunc {
+func AcceptLanguage(ctx context.Context) string {
+	acceptLanguage, _ := ctx.Value(acceptLanguageKey).(string)
+	return acceptLanguage
+}
+
+func (r *RouterAPI) AddRoute(route Route) error {
+	switch {
+	case route.Name == "":
+		return errors.New("route " + route.Method + " " + route.Path + " has no name")
+	case route.Handler == nil:
+		return errors.New("route " + route.Name + " has no handler")
+	case route.Method != "GET" && route.Method != "POST" && route.Method != "PUT" && route.Method != "DELETE":
+		return errors.New("route " + route.Name + " has an unsupported method " + route.Method)
+	case r.routes[route.Name]:
+		return errors.New("route " + route.Name + " is already registered")
+	case r.routes[route.Method+" "+route.Path]:
+		return errors.New("route " + route.Name + " duplicates " + route.Method + " " + route.Path)
+	}
+	r.routes[route.Name] = true
+	r.routes[route.Method+" "+route.Path] = true
+	return r.router.Path(route.Path).Methods(route.Method).HandlerFunc(func(route Route) http.HandlerFunc {
 		return func(w http.ResponseWriter, req *http.Request) {
 			tStart := time.Now()
 			var err error
+			var userID int
 			if route.Auth {
-				err = r.requireAuth(*req)
+				userID, err = r.requireAuth(*req)
+			}
+			if err == nil && req.ParseForm() != nil {
+				err = apierror.BadRequest("request parameters are invalid")
 			}
 			if tls {
 				ensureSTS(w)
 			}
 			w.Header().Set("Content-Type", "application/json")
 			if err != nil {
-				errorWriter(w, http.StatusInternalServerError, err)
+				errorWriter(w, err)
 				return
 			} else {
-				inputs := []reflect.Value{}
-				inputs = append(inputs, reflect.ValueOf(mux.Vars(req)))
-				queries := req.URL.Query()
-				inputs = append(inputs, reflect.ValueOf(queries))
-				out := reflect.ValueOf(route.HandlerStruct).MethodByName(route.HandlerMethod).Call(inputs)
-				resp := out[0].Interface()
-				if out[len(out)-1].Interface() != nil {
-					err = out[len(out)-1].Interface().(error)
+				vars := map[string]string{}
+				for key, value := range mux.Vars(req) {
+					if vars[key], err = url.PathUnescape(value); err != nil {
+						errorWriter(w, apierror.BadRequest(key+" is invalid"))
+						return
+					}
+				}
+				ctx := context.WithValue(req.Context(), acceptLanguageKey, req.Header.Get("Accept-Language"))
+				if route.Auth {
+					ctx = context.WithValue(ctx, userIDKey, userID)
+				}
+				if route.Method == "GET" {
+					format, err := exportFormat(req)
+					if err == nil && format != "" {
+						err = exportAll(ctx, w, route, vars, req.Form, format)
+						if err == nil {
+							return
+						}
+					}
+					if err != nil {
+						errorWriter(w, err)
+						return
+					}
 				}
+				resp, metadata, err := route.Handler.Handle(ctx, vars, req.Form)
 				tEnd := time.Now()
 				t := tEnd.Sub(tStart)
 				if err != nil {
-					errorWriter(w, http.StatusInternalServerError, err)
+					errorWriter(w, err)
 					return
 				}
 				jsonResp, err := json.Marshal(resp)
 				if err != nil {
-					errorWriter(w, http.StatusInternalServerError, err)
+					errorWriter(w, err)
 					return
 				}
-				metadataToHeaders(w, out[1].Interface().(map[string]string))
+				metadataToHeaders(w, metadata)
+				if idAfter, exists := metadata["Id-After"]; exists {
+					w.Header().Set("Link", nextLink(req, idAfter))
+				}
 				w.Header().Set("X-Runtime", t.String())
 				if route.Method == "POST" {
 					w.WriteHeader(http.StatusCreated)
@@ -105,7 +172,7 @@ func (r *RouterAPI) AddRoute(route Route) {
 				w.Write(jsonResp)
 			}
 		}
-	}(route))
+	}(route)).GetError()
 }
 
 func ensureSTS(w http.ResponseWriter) {
@@ -118,27 +185,103 @@ func metadataToHeaders(w http.ResponseWriter, metadata map[string]string) {
 	}
 }
 
-func (r *RouterAPI) requireAuth(req http.Request) error {
-	nr, err := http.NewRequest("GET", u.String()+"/api/v4/version", nil)
+func nextLink(req *http.Request, idAfter string) string {
+	next := *req.URL
+	next.Host = req.Host
+	next.Scheme = "http"
+	if tls {
+		next.Scheme = "https"
+	}
+	query := req.URL.Query()
+	query.Set("id_after", idAfter)
+	next.RawQuery = query.Encode()
+	return "<" + next.String() + ">; rel=\"next\""
+}
+
+func (r *RouterAPI) requireAuth(req http.Request) (int, error) {
+	var user struct {
+		ID int `json:"id"`
+	}
+	nr, err := http.NewRequest("GET", u.String()+"/api/v4/user", nil)
 	if err != nil {
-		panic(err)
+		return 0, apierror.Internal(err)
 	}
 	nr.Header = req.Header
 	resp, err := nc.Do(nr)
 	if err != nil {
-		panic(err)
+		return 0, apierror.Internal(err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return 0, apierror.Unauthorized()
+	case http.StatusForbidden:
+		return 0, apierror.Forbidden()
+	default:
+		return 0, apierror.Internal(errors.New("GitLab returned " + resp.Status + " for the current user"))
 	}
-	if resp.StatusCode != 200 {
-		err = errors.New("401 Unauthorized")
-	} else {
-		err = nil
+	if err = json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return 0, apierror.Internal(err)
 	}
-	return err
+	return user.ID, nil
 }
 
-func errorWriter(w http.ResponseWriter, statusCode int, e error) {
+// requireScope checks that the request token was granted scope, as a personal,
+// project or group access token or else as an OAuth token. Other credentials,
+// such as session cookies or job tokens, are refused.
+func (r *RouterAPI) requireScope(req http.Request, scope string) error {
+	var token struct {
+		Scopes []string `json:"scopes"`
+	}
+	for _, path := range []string{"/api/v4/personal_access_tokens/self", "/oauth/token/info"} {
+		found, err := gitlabGet(req, path, &token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		for _, s := range token.Scopes {
+			if s == scope {
+				return nil
+			}
+		}
+		return apierror.Forbidden()
+	}
+	return apierror.Forbidden()
+}
+
+// gitlabGet decodes the GitLab response to path, requested with the credentials
+// of req, into v. It reports false when GitLab does not return 200.
+func gitlabGet(req http.Request, path string, v interface{}) (bool, error) {
+	nr, err := http.NewRequest("GET", u.String()+path, nil)
+	if err != nil {
+		return false, apierror.Internal(err)
+	}
+	nr.Header = req.Header
+	resp, err := nc.Do(nr)
+	if err != nil {
+		return false, apierror.Internal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, apierror.Internal(err)
+	}
+	return true, nil
+}
+
+func errorWriter(w http.ResponseWriter, e error) {
+	statusCode, body := apierror.Response(e)
+	if statusCode >= http.StatusInternalServerError {
+		log.Println(e.Error())
+	}
+	jsonBody, _ := json.Marshal(body)
 	w.WriteHeader(statusCode)
-	w.Write([]byte("{\"message\": \"" + e.Error() + "\"}"))
+	w.Write(jsonBody)
 }
 
 func unixSocketDial(proto, addr string) (conn net.Conn, err error) {